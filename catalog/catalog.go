@@ -0,0 +1,217 @@
+// Package catalog keeps an on-disk copy of the Dune dataset catalog, so tooling can search
+// datasets and read their columns without calling the API every time.
+//
+// The catalog is a JSON file holding the results of paging through SearchDatasets, refreshed
+// when older than a TTL, and the datasets fetched with GetDataset, each with its own fetch time.
+// Search runs offline over the cached results with the filters of SearchDatasetsRequest.
+//
+// The file is written at the end of each Refresh, and datasets fetched since are written by Close.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/internal/fileutil"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// DefaultTTL is the age after which cached entries are refreshed when Options.TTL is zero
+const DefaultTTL = 24 * time.Hour
+
+// fileVersion is the version of the file format, files of another version are ignored
+const fileVersion = 1
+
+// ErrNotCached is returned in offline mode for datasets missing from the cache
+var ErrNotCached = errors.New("dataset is not in the catalog cache")
+
+// Options configures a Catalog
+type Options struct {
+	// TTL is the age after which the search results and datasets are fetched again, DefaultTTL if zero
+	TTL time.Duration
+	// Scope restricts the datasets cached by Refresh, such as to some blockchains. Its Query,
+	// Limit and Offset are ignored; schemas and metadata are always included.
+	Scope models.SearchDatasetsRequest
+	// Offline serves the cache as is, however old, and never calls the API
+	Offline bool
+}
+
+// cachedDataset is a dataset fetched with GetDataset
+type cachedDataset struct {
+	FetchedAt time.Time              `json:"fetched_at"`
+	Dataset   models.DatasetResponse `json:"dataset"`
+}
+
+// file is the on-disk format of the catalog
+type file struct {
+	Version     int                          `json:"version"`
+	RefreshedAt time.Time                    `json:"refreshed_at"`
+	Scope       models.SearchDatasetsRequest `json:"scope"`
+	Results     []models.SearchDatasetResult `json:"results"`
+	Datasets    map[string]cachedDataset     `json:"datasets"`
+}
+
+// Catalog is a dataset catalog cached in a file. It is safe for concurrent use.
+type Catalog struct {
+	client  dune.DuneClient
+	path    string
+	options Options
+
+	mu   sync.Mutex
+	data file
+	// texts holds the search text of each of data.Results, decoded once when they are loaded
+	texts []string
+	// dirty is set when datasets were fetched since the file was last written
+	dirty bool
+}
+
+// Open returns the catalog cached at path, which is created on the first refresh if it does not exist
+func Open(client dune.DuneClient, path string, options Options) (*Catalog, error) {
+	if options.TTL == 0 {
+		options.TTL = DefaultTTL
+	}
+	options.Scope = scope(options.Scope)
+	c := &Catalog{
+		client:  client,
+		path:    path,
+		options: options,
+		data:    file{Version: fileVersion, Scope: options.Scope, Datasets: map[string]cachedDataset{}},
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data file
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+	if data.Version != fileVersion {
+		// an older format, rebuilt on the next refresh
+		return c, nil
+	}
+	if data.Datasets == nil {
+		data.Datasets = map[string]cachedDataset{}
+	}
+	if !reflect.DeepEqual(data.Scope, options.Scope) {
+		// the search results were cached for another scope
+		data.Results, data.RefreshedAt = nil, time.Time{}
+	}
+	data.Scope = options.Scope
+	c.data = data
+	c.texts = searchTexts(data.Results)
+	return c, nil
+}
+
+// scope returns the search request used to populate the catalog
+func scope(req models.SearchDatasetsRequest) models.SearchDatasetsRequest {
+	include := true
+	req.Query, req.Limit, req.Offset = nil, nil, nil
+	req.IncludeSchema, req.IncludeMetadata = &include, &include
+	return req
+}
+
+// RefreshedAt returns when the search results were last refreshed, the zero time if never
+func (c *Catalog) RefreshedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data.RefreshedAt
+}
+
+// Stale reports whether the search results are older than the TTL
+func (c *Catalog) Stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stale(c.data.RefreshedAt)
+}
+
+func (c *Catalog) stale(fetchedAt time.Time) bool {
+	return fetchedAt.IsZero() || time.Since(fetchedAt) > c.options.TTL
+}
+
+// Refresh pages through the catalog with SearchDatasets and replaces the cached search results.
+// The cache is left untouched if any page fails.
+func (c *Catalog) Refresh(ctx context.Context) error {
+	if c.options.Offline {
+		return errors.New("cannot refresh an offline catalog")
+	}
+	results, err := c.client.SearchAll(ctx, c.options.Scope, models.PaginationOptions{}).Collect()
+	if err != nil {
+		return fmt.Errorf("failed to refresh the catalog: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Results = results
+	c.data.RefreshedAt = time.Now()
+	c.texts = searchTexts(results)
+	return c.save()
+}
+
+// RefreshIfStale refreshes the search results if they are older than the TTL. Offline catalogs
+// are never refreshed.
+func (c *Catalog) RefreshIfStale(ctx context.Context) error {
+	if c.options.Offline || !c.Stale() {
+		return nil
+	}
+	return c.Refresh(ctx)
+}
+
+// GetDataset returns a dataset like DuneClient.GetDataset, from the cache if it was fetched
+// less than the TTL ago. Offline catalogs return ErrNotCached for datasets never fetched.
+// Fetched datasets are kept in memory until the next Refresh or Close writes the file.
+func (c *Catalog) GetDataset(slug string) (*models.DatasetResponse, error) {
+	c.mu.Lock()
+	cached, ok := c.data.Datasets[slug]
+	c.mu.Unlock()
+	if ok && (c.options.Offline || !c.stale(cached.FetchedAt)) {
+		return &cached.Dataset, nil
+	}
+	if c.options.Offline {
+		return nil, fmt.Errorf("%s: %w", slug, ErrNotCached)
+	}
+
+	dataset, err := c.client.GetDataset(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Datasets[slug] = cachedDataset{FetchedAt: time.Now(), Dataset: *dataset}
+	c.dirty = true
+	return dataset, nil
+}
+
+// Close writes the datasets fetched since the file was last written. The catalog remains usable.
+func (c *Catalog) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	return c.save()
+}
+
+// save writes the catalog to its path. c.mu must be held.
+func (c *Catalog) save() error {
+	content, err := json.Marshal(c.data)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteFile(c.path, content, 0o644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
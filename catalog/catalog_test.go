@@ -0,0 +1,222 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/config"
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) dune.DuneClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return dune.NewDuneClient(&config.Env{
+		APIKey: "test-api-key",
+		Host:   server.URL,
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var testResults = []models.SearchDatasetResult{
+	{
+		FullName:    "dex.trades",
+		Category:    "spell",
+		DatasetType: ptr("spell"),
+		Blockchains: []string{"ethereum", "base"},
+		Description: ptr("All DEX trades"),
+		Schema:      json.RawMessage(`[{"name": "amount_usd", "type": "double"}, {"name": "tx_hash", "type": "varbinary"}]`),
+		Metadata:    &models.SearchDatasetMetadata{PageRankScore: ptr(0.9)},
+	},
+	{
+		FullName:    "uniswap_v3_ethereum.Pair_evt_Swap",
+		Category:    "decoded",
+		DatasetType: ptr("decoded_table"),
+		Blockchains: []string{"ethereum"},
+		Schema:      json.RawMessage(`{"columns": [{"name": "amount0"}, {"name": "tx_hash"}]}`),
+		Metadata:    &models.SearchDatasetMetadata{PageRankScore: ptr(0.5), ProjectName: ptr("uniswap")},
+	},
+	{
+		FullName:    "prices.usd",
+		Category:    "spell",
+		DatasetType: ptr("spell"),
+		Blockchains: []string{"ethereum", "solana"},
+		Description: ptr("Token prices in USD"),
+		Metadata:    &models.SearchDatasetMetadata{PageRankScore: ptr(0.7)},
+	},
+	{
+		FullName:    "dune.my_team.trades_backup",
+		Category:    "uploaded",
+		DatasetType: ptr("uploaded_table"),
+		Visibility:  ptr("private"),
+		OwnerScope:  ptr("team"),
+	},
+}
+
+// catalogServer serves testResults from the search endpoint, two per page, and counts requests
+type catalogServer struct {
+	searches int
+	gets     int
+	scope    models.SearchDatasetsRequest
+}
+
+func (s *catalogServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch r.URL.Path {
+	case "/api/v1/datasets/search":
+		s.searches++
+		var req models.SearchDatasetsRequest
+		json.Unmarshal(body, &req)
+		s.scope = req
+		offset := int(*req.Offset)
+		end := min(offset+2, len(testResults))
+		resp := models.SearchDatasetsResponse{Total: int32(len(testResults)), Results: testResults[offset:end]}
+		if end < len(testResults) {
+			next := int32(end)
+			resp.Pagination = models.SearchDatasetsPagination{HasMore: true, NextOffset: &next}
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/api/v1/datasets/dex.trades":
+		s.gets++
+		json.NewEncoder(w).Encode(models.DatasetResponse{
+			FullName: "dex.trades",
+			Type:     "spell",
+			Columns:  []models.DatasetColumn{{Name: "amount_usd", Type: "double"}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(dune.ErrorResponse{Error: "not found"})
+	}
+}
+
+func TestRefreshAndSearch(t *testing.T) {
+	server := &catalogServer{}
+	client := newTestClient(t, server.handle)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	c, err := Open(client, path, Options{})
+	require.NoError(t, err)
+	require.True(t, c.Stale())
+	require.NoError(t, c.RefreshIfStale(context.Background()))
+	require.Equal(t, 2, server.searches)
+	require.True(t, *server.scope.IncludeSchema)
+	require.False(t, c.Stale())
+	require.NoError(t, c.RefreshIfStale(context.Background()))
+	require.Equal(t, 2, server.searches)
+
+	// reopened offline, the catalog is served from disk
+	c, err = Open(client, path, Options{Offline: true})
+	require.NoError(t, err)
+	require.False(t, c.RefreshedAt().IsZero())
+
+	names := func(resp *models.SearchDatasetsResponse) []string {
+		var out []string
+		for _, result := range resp.Results {
+			out = append(out, result.FullName)
+		}
+		return out
+	}
+	require.Equal(t, []string{"dex.trades", "prices.usd", "uniswap_v3_ethereum.Pair_evt_Swap"},
+		names(c.Search(models.SearchDatasetsRequest{})))
+	// name matches first, then column and description matches by page rank
+	require.Equal(t, []string{"dex.trades", "uniswap_v3_ethereum.Pair_evt_Swap"},
+		names(c.Search(models.SearchDatasetsRequest{Query: ptr("TX_HASH")})))
+	require.Equal(t, []string{"prices.usd"}, names(c.Search(models.SearchDatasetsRequest{Query: ptr("usd prices")})))
+	require.Equal(t, []string{"uniswap_v3_ethereum.Pair_evt_Swap"},
+		names(c.Search(models.SearchDatasetsRequest{Query: ptr("uniswap swap")})))
+	require.Equal(t, []string{"prices.usd"}, names(c.Search(models.SearchDatasetsRequest{Blockchains: []string{"solana"}})))
+	require.Equal(t, []string{"uniswap_v3_ethereum.Pair_evt_Swap"},
+		names(c.Search(models.SearchDatasetsRequest{DatasetTypes: []string{"decoded_table"}})))
+	require.Equal(t, []string{"dex.trades"}, names(c.Search(models.SearchDatasetsRequest{Schemas: []string{"dex"}})))
+	require.Equal(t, []string{"dune.my_team.trades_backup"}, names(c.Search(models.SearchDatasetsRequest{
+		OwnerScope:     ptr("team"),
+		IncludePrivate: ptr(true),
+	})))
+
+	page := c.Search(models.SearchDatasetsRequest{Categories: []string{"spell"}, Limit: ptr(int32(1))})
+	require.Equal(t, int32(2), page.Total)
+	require.Equal(t, []string{"dex.trades"}, names(page))
+	require.Nil(t, page.Results[0].Schema)
+	require.Nil(t, page.Results[0].Metadata)
+	require.True(t, page.Pagination.HasMore)
+	require.Equal(t, int32(1), *page.Pagination.NextOffset)
+
+	page = c.Search(models.SearchDatasetsRequest{
+		Categories:    []string{"spell"},
+		Offset:        page.Pagination.NextOffset,
+		IncludeSchema: ptr(true),
+	})
+	require.Equal(t, []string{"prices.usd"}, names(page))
+	require.False(t, page.Pagination.HasMore)
+	require.Equal(t, 2, server.searches)
+	require.Error(t, c.Refresh(context.Background()))
+}
+
+func TestGetDataset(t *testing.T) {
+	server := &catalogServer{}
+	client := newTestClient(t, server.handle)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	offline, err := Open(client, path, Options{Offline: true})
+	require.NoError(t, err)
+	_, err = offline.GetDataset("dex.trades")
+	require.ErrorIs(t, err, ErrNotCached)
+
+	c, err := Open(client, path, Options{})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		dataset, err := c.GetDataset("dex.trades")
+		require.NoError(t, err)
+		require.Equal(t, "amount_usd", dataset.Columns[0].Name)
+	}
+	require.Equal(t, 1, server.gets)
+	// fetched datasets are only written on Close
+	require.NoFileExists(t, path)
+	require.NoError(t, c.Close())
+	require.FileExists(t, path)
+
+	offline, err = Open(client, path, Options{Offline: true})
+	require.NoError(t, err)
+	dataset, err := offline.GetDataset("dex.trades")
+	require.NoError(t, err)
+	require.Equal(t, "dex.trades", dataset.FullName)
+	require.Equal(t, 1, server.gets)
+}
+
+func TestScopeChangeDropsResults(t *testing.T) {
+	server := &catalogServer{}
+	client := newTestClient(t, server.handle)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	c, err := Open(client, path, Options{})
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh(context.Background()))
+
+	c, err = Open(client, path, Options{Scope: models.SearchDatasetsRequest{Blockchains: []string{"ethereum"}}})
+	require.NoError(t, err)
+	require.True(t, c.Stale())
+	require.Empty(t, c.Search(models.SearchDatasetsRequest{}).Results)
+}
+
+func TestValidateSQLSuggestsCachedTables(t *testing.T) {
+	server := &catalogServer{}
+	c, err := Open(newTestClient(t, server.handle), filepath.Join(t.TempDir(), "catalog.json"), Options{})
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh(context.Background()))
+
+	err = dune.ValidateSQL(c, "SELECT amount_usd FROM dex.trade")
+	require.EqualError(t, err, "invalid SQL: line 1: table dex.trade does not exist, did you mean dex.trades?")
+	err = dune.ValidateSQL(c, "SELECT amount_usd, tx_hash FROM dex.trades")
+	require.EqualError(t, err, "invalid SQL: line 1: column tx_hash does not exist in dex.trades")
+	require.Equal(t, 1, server.gets)
+}
@@ -0,0 +1,306 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/internal/fileutil"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// SchemaSnapshot records the columns of a set of datasets at a point in time
+type SchemaSnapshot struct {
+	TakenAt  time.Time                         `json:"taken_at"`
+	Datasets map[string][]models.DatasetColumn `json:"datasets"`
+	// Failed lists the watched datasets never fetched successfully, which have no columns yet
+	Failed []string `json:"failed,omitempty"`
+}
+
+// LoadSchemaSnapshot reads a snapshot saved by a SchemaWatcher
+func LoadSchemaSnapshot(path string) (*SchemaSnapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot SchemaSnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse schema snapshot %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+func (s *SchemaSnapshot) save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(path, content, 0o644)
+}
+
+// SchemaChangeKind is the kind of a SchemaChange
+type SchemaChangeKind string
+
+const (
+	ColumnAdded       SchemaChangeKind = "column_added"
+	ColumnRemoved     SchemaChangeKind = "column_removed"
+	ColumnRetyped     SchemaChangeKind = "column_retyped"
+	ColumnNullability SchemaChangeKind = "nullable_changed"
+	DatasetAdded      SchemaChangeKind = "dataset_added"
+	DatasetRemoved    SchemaChangeKind = "dataset_removed"
+)
+
+// SchemaChange is a difference between two snapshots of a dataset
+type SchemaChange struct {
+	Dataset string           `json:"dataset"`
+	Kind    SchemaChangeKind `json:"kind"`
+	// Column is empty for dataset changes
+	Column      string `json:"column,omitempty"`
+	OldType     string `json:"old_type,omitempty"`
+	NewType     string `json:"new_type,omitempty"`
+	OldNullable bool   `json:"old_nullable,omitempty"`
+	NewNullable bool   `json:"new_nullable,omitempty"`
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case ColumnAdded:
+		return fmt.Sprintf("%s: + %s %s", c.Dataset, c.Column, c.NewType)
+	case ColumnRemoved:
+		return fmt.Sprintf("%s: - %s %s", c.Dataset, c.Column, c.OldType)
+	case ColumnRetyped:
+		return fmt.Sprintf("%s: ~ %s %s -> %s", c.Dataset, c.Column, c.OldType, c.NewType)
+	case ColumnNullability:
+		return fmt.Sprintf("%s: ~ %s nullable %v -> %v", c.Dataset, c.Column, c.OldNullable, c.NewNullable)
+	case DatasetAdded:
+		return fmt.Sprintf("%s: new dataset", c.Dataset)
+	case DatasetRemoved:
+		return fmt.Sprintf("%s: no longer watched", c.Dataset)
+	}
+	return fmt.Sprintf("%s: %s %s", c.Dataset, c.Kind, c.Column)
+}
+
+// SchemaReport is the result of a SchemaWatcher check
+type SchemaReport struct {
+	// Previous is when the previous snapshot was taken, the zero time on the first check
+	Previous time.Time      `json:"previous"`
+	Current  time.Time      `json:"current"`
+	Changes  []SchemaChange `json:"changes"`
+	// Errors holds why the datasets that could not be fetched failed, by name. They keep their
+	// previous columns and are compared again at the next check.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// HasChanges reports whether the report holds changes
+func (r *SchemaReport) HasChanges() bool {
+	return len(r.Changes) > 0
+}
+
+// String renders the report as text, a line per change then a line per error
+func (r *SchemaReport) String() string {
+	var b strings.Builder
+	switch {
+	case r.Previous.IsZero():
+		fmt.Fprintf(&b, "first snapshot taken at %s\n", r.Current.Format(time.RFC3339))
+	case !r.HasChanges():
+		fmt.Fprintf(&b, "no changes since %s\n", r.Previous.Format(time.RFC3339))
+	default:
+		fmt.Fprintf(&b, "%d changes since %s:\n", len(r.Changes), r.Previous.Format(time.RFC3339))
+	}
+	for _, change := range r.Changes {
+		b.WriteString(change.String())
+		b.WriteString("\n")
+	}
+	var failed []string
+	for name := range r.Errors {
+		failed = append(failed, name)
+	}
+	slices.Sort(failed)
+	for _, name := range failed {
+		fmt.Fprintf(&b, "%s: check failed: %s\n", name, r.Errors[name])
+	}
+	return b.String()
+}
+
+// DiffSchemas returns the changes from previous to current, sorted by dataset
+func DiffSchemas(previous, current *SchemaSnapshot) []SchemaChange {
+	var names []string
+	for name := range previous.Datasets {
+		names = append(names, name)
+	}
+	for name := range current.Datasets {
+		if _, ok := previous.Datasets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []SchemaChange
+	for _, name := range names {
+		before, wasWatched := previous.Datasets[name]
+		after, isWatched := current.Datasets[name]
+		switch {
+		case !wasWatched:
+			changes = append(changes, SchemaChange{Dataset: name, Kind: DatasetAdded})
+		case !isWatched:
+			changes = append(changes, SchemaChange{Dataset: name, Kind: DatasetRemoved})
+		default:
+			changes = append(changes, diffColumns(name, before, after)...)
+		}
+	}
+	return changes
+}
+
+// diffColumns compares the columns of a dataset, listing changes in the order of the current columns
+func diffColumns(dataset string, before, after []models.DatasetColumn) []SchemaChange {
+	old := make(map[string]models.DatasetColumn, len(before))
+	for _, c := range before {
+		old[c.Name] = c
+	}
+	var changes []SchemaChange
+	kept := map[string]bool{}
+	for _, c := range after {
+		prev, ok := old[c.Name]
+		if !ok {
+			changes = append(changes, SchemaChange{
+				Dataset: dataset, Kind: ColumnAdded, Column: c.Name, NewType: c.Type, NewNullable: c.Nullable,
+			})
+			continue
+		}
+		kept[c.Name] = true
+		if !strings.EqualFold(prev.Type, c.Type) {
+			changes = append(changes, SchemaChange{
+				Dataset: dataset, Kind: ColumnRetyped, Column: c.Name, OldType: prev.Type, NewType: c.Type,
+			})
+		}
+		if prev.Nullable != c.Nullable {
+			changes = append(changes, SchemaChange{
+				Dataset: dataset, Kind: ColumnNullability, Column: c.Name,
+				OldNullable: prev.Nullable, NewNullable: c.Nullable,
+			})
+		}
+	}
+	for _, c := range before {
+		if !kept[c.Name] {
+			changes = append(changes, SchemaChange{
+				Dataset: dataset, Kind: ColumnRemoved, Column: c.Name, OldType: c.Type, OldNullable: c.Nullable,
+			})
+		}
+	}
+	return changes
+}
+
+// SchemaWatcher detects column changes in a set of datasets by comparing the columns returned
+// by GetDataset with a snapshot saved in a file at the previous check.
+type SchemaWatcher struct {
+	client   dune.DuneClient
+	path     string
+	datasets []string
+	// OnChange, if set, is called with the reports of the checks that found changes
+	OnChange func(*SchemaReport)
+}
+
+// NewSchemaWatcher returns a watcher of datasets keeping its snapshot in the file at path
+func NewSchemaWatcher(client dune.DuneClient, path string, datasets []string) *SchemaWatcher {
+	return &SchemaWatcher{
+		client:   client,
+		path:     path,
+		datasets: datasets,
+	}
+}
+
+// Snapshot fetches the current columns of the watched datasets. The datasets that cannot be
+// fetched are returned as errors by name.
+func (w *SchemaWatcher) Snapshot(ctx context.Context) (*SchemaSnapshot, map[string]error, error) {
+	snapshot := &SchemaSnapshot{TakenAt: time.Now().UTC(), Datasets: map[string][]models.DatasetColumn{}}
+	failed := map[string]error{}
+	for _, name := range w.datasets {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		dataset, err := w.client.GetDataset(name)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		snapshot.Datasets[name] = dataset.Columns
+	}
+	return snapshot, failed, nil
+}
+
+// Check takes a snapshot, diffs it against the saved one and saves it in its place. The first
+// check only saves the snapshot. Datasets that cannot be fetched are reported in the Errors of the
+// report, not as changes, and keep their previous columns in the saved snapshot, so they are compared
+// again at the next check. A dataset fetched for the first time after failing only gets its columns
+// saved, it is not reported as added.
+func (w *SchemaWatcher) Check(ctx context.Context) (*SchemaReport, error) {
+	previous, err := LoadSchemaSnapshot(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		previous = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	current, failed, err := w.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &SchemaReport{Current: current.TakenAt}
+	if previous != nil {
+		report.Previous = previous.TakenAt
+		if previous.Datasets == nil {
+			previous.Datasets = map[string][]models.DatasetColumn{}
+		}
+		for name := range failed {
+			if columns, ok := previous.Datasets[name]; ok {
+				current.Datasets[name] = columns
+			}
+		}
+		for _, name := range previous.Failed {
+			// watched before but never fetched, its first columns are the baseline
+			if columns, ok := current.Datasets[name]; ok {
+				previous.Datasets[name] = columns
+			}
+		}
+		report.Changes = DiffSchemas(previous, current)
+	}
+	for name, err := range failed {
+		if report.Errors == nil {
+			report.Errors = map[string]string{}
+		}
+		report.Errors[name] = err.Error()
+		if _, ok := current.Datasets[name]; !ok {
+			current.Failed = append(current.Failed, name)
+		}
+	}
+	slices.Sort(current.Failed)
+
+	if err := current.save(w.path); err != nil {
+		return nil, fmt.Errorf("failed to save schema snapshot: %w", err)
+	}
+	if report.HasChanges() && w.OnChange != nil {
+		w.OnChange(report)
+	}
+	return report, nil
+}
+
+// Watch checks the datasets every interval until ctx is done, returning ctx.Err() then.
+// Changes are reported through OnChange.
+func (w *SchemaWatcher) Watch(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := w.Check(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
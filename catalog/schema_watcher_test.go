@@ -0,0 +1,128 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	previous := &SchemaSnapshot{Datasets: map[string][]models.DatasetColumn{
+		"dex.trades": {
+			{Name: "block_time", Type: "timestamp(3) with time zone"},
+			{Name: "amount", Type: "double", Nullable: true},
+			{Name: "tx_from", Type: "varbinary"},
+			{Name: "project", Type: "varchar"},
+		},
+		"old.table": {{Name: "x", Type: "bigint"}},
+	}}
+	current := &SchemaSnapshot{Datasets: map[string][]models.DatasetColumn{
+		"dex.trades": {
+			{Name: "block_time", Type: "TIMESTAMP(3) WITH TIME ZONE"},
+			{Name: "amount", Type: "uint256", Nullable: false},
+			{Name: "project", Type: "varchar", Nullable: true},
+			{Name: "tx_to", Type: "varbinary"},
+		},
+		"prices.usd": {{Name: "price", Type: "double"}},
+	}}
+
+	changes := DiffSchemas(previous, current)
+
+	require.Equal(t, []SchemaChange{
+		{Dataset: "dex.trades", Kind: ColumnRetyped, Column: "amount", OldType: "double", NewType: "uint256"},
+		{Dataset: "dex.trades", Kind: ColumnNullability, Column: "amount", OldNullable: true},
+		{Dataset: "dex.trades", Kind: ColumnNullability, Column: "project", NewNullable: true},
+		{Dataset: "dex.trades", Kind: ColumnAdded, Column: "tx_to", NewType: "varbinary"},
+		{Dataset: "dex.trades", Kind: ColumnRemoved, Column: "tx_from", OldType: "varbinary"},
+		{Dataset: "old.table", Kind: DatasetRemoved},
+		{Dataset: "prices.usd", Kind: DatasetAdded},
+	}, changes)
+	require.Equal(t, "dex.trades: ~ amount double -> uint256", changes[0].String())
+	require.Equal(t, "dex.trades: - tx_from varbinary", changes[4].String())
+}
+
+func TestSchemaWatcherCheck(t *testing.T) {
+	columns := []models.DatasetColumn{{Name: "amount", Type: "double"}}
+	available := true
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/datasets/dex.trades" || !available {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			return
+		}
+		json.NewEncoder(w).Encode(models.DatasetResponse{FullName: "dex.trades", Type: "spell", Columns: columns})
+	})
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	watcher := NewSchemaWatcher(client, path, []string{"dex.trades"})
+	var notified []*SchemaReport
+	watcher.OnChange = func(report *SchemaReport) {
+		notified = append(notified, report)
+	}
+
+	report, err := watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.True(t, report.Previous.IsZero())
+	require.False(t, report.HasChanges())
+
+	report, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.Previous.IsZero())
+	require.False(t, report.HasChanges())
+	require.Empty(t, notified)
+
+	// a failed fetch keeps the previous columns, so the change is still reported afterwards
+	available = false
+	report, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.HasChanges())
+	require.Contains(t, report.Errors, "dex.trades")
+	require.Contains(t, report.String(), "dex.trades: check failed: ")
+
+	available = true
+	columns = []models.DatasetColumn{{Name: "amount", Type: "uint256"}}
+	report, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []SchemaChange{
+		{Dataset: "dex.trades", Kind: ColumnRetyped, Column: "amount", OldType: "double", NewType: "uint256"},
+	}, report.Changes)
+	require.Contains(t, report.String(), "1 changes since")
+	require.Len(t, notified, 1)
+
+	snapshot, err := LoadSchemaSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, columns, snapshot.Datasets["dex.trades"])
+}
+
+func TestSchemaWatcherCheckFailedFirstFetch(t *testing.T) {
+	available := false
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal error"})
+			return
+		}
+		json.NewEncoder(w).Encode(models.DatasetResponse{
+			FullName: "dex.trades", Type: "spell", Columns: []models.DatasetColumn{{Name: "amount", Type: "double"}},
+		})
+	})
+	watcher := NewSchemaWatcher(client, filepath.Join(t.TempDir(), "snapshot.json"), []string{"dex.trades"})
+
+	report, err := watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, report.Errors, "dex.trades")
+	report, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, report.Errors, "dex.trades")
+
+	// the dataset was watched all along, its first columns are not reported as a new dataset
+	available = true
+	report, err = watcher.Check(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.False(t, report.HasChanges())
+}
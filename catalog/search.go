@@ -0,0 +1,180 @@
+package catalog
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// DefaultSearchLimit is the page size of Search when req.Limit is not set
+const DefaultSearchLimit = 20
+
+// Search searches the cached results offline with the filters of req, without refreshing them:
+//   - Query matches when each of its words is found, case insensitively, in the full name,
+//     description, project or contract name, blockchains or column names of a dataset
+//   - Categories, Blockchains, DatasetTypes and OwnerScope keep the datasets matching any of the values
+//   - Schemas keeps the datasets whose schema, the part of the full name before the table, is listed
+//   - private datasets are skipped unless IncludePrivate is set
+//   - schemas and metadata are only returned when IncludeSchema and IncludeMetadata are set
+//
+// Datasets whose full name matches the query come first, then the others by page rank.
+// Limit and Offset paginate the matches like the API does.
+func (c *Catalog) Search(req models.SearchDatasetsRequest) *models.SearchDatasetsResponse {
+	c.mu.Lock()
+	results, texts := c.data.Results, c.texts
+	c.mu.Unlock()
+
+	var terms []string
+	if req.Query != nil {
+		terms = strings.Fields(strings.ToLower(*req.Query))
+	}
+
+	type match struct {
+		result    models.SearchDatasetResult
+		nameMatch bool
+		rank      float64
+	}
+	var matches []match
+	for i, result := range results {
+		if !matchesFilters(result, req) {
+			continue
+		}
+		name := strings.ToLower(result.FullName)
+		nameMatch := len(terms) > 0
+		text := texts[i]
+		found := true
+		for _, term := range terms {
+			if !strings.Contains(name, term) {
+				nameMatch = false
+			}
+			if !strings.Contains(text, term) {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		m := match{result: result, nameMatch: nameMatch}
+		if result.Metadata != nil && result.Metadata.PageRankScore != nil {
+			m.rank = *result.Metadata.PageRankScore
+		}
+		matches = append(matches, m)
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		switch {
+		case a.nameMatch != b.nameMatch:
+			if a.nameMatch {
+				return -1
+			}
+			return 1
+		case a.rank > b.rank:
+			return -1
+		case a.rank < b.rank:
+			return 1
+		}
+		return strings.Compare(a.result.FullName, b.result.FullName)
+	})
+
+	limit, offset := int32(DefaultSearchLimit), int32(0)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if req.Offset != nil && *req.Offset > 0 {
+		offset = *req.Offset
+	}
+	resp := &models.SearchDatasetsResponse{
+		Total:      int32(len(matches)),
+		Results:    []models.SearchDatasetResult{},
+		Pagination: models.SearchDatasetsPagination{Limit: limit, Offset: offset},
+	}
+	for i := offset; i < offset+limit && int(i) < len(matches); i++ {
+		result := matches[i].result
+		if req.IncludeSchema == nil || !*req.IncludeSchema {
+			result.Schema = nil
+		}
+		if req.IncludeMetadata == nil || !*req.IncludeMetadata {
+			result.Metadata = nil
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	if next := offset + limit; int(next) < len(matches) {
+		resp.Pagination.HasMore = true
+		resp.Pagination.NextOffset = &next
+	}
+	return resp
+}
+
+// matchesFilters reports whether a result passes the filters of req other than the query
+func matchesFilters(result models.SearchDatasetResult, req models.SearchDatasetsRequest) bool {
+	if len(req.Categories) > 0 && !containsFold(req.Categories, result.Category) {
+		return false
+	}
+	if len(req.Blockchains) > 0 && !slices.ContainsFunc(result.Blockchains, func(chain string) bool {
+		return containsFold(req.Blockchains, chain)
+	}) {
+		return false
+	}
+	if len(req.DatasetTypes) > 0 && (result.DatasetType == nil || !containsFold(req.DatasetTypes, *result.DatasetType)) {
+		return false
+	}
+	if len(req.Schemas) > 0 && !containsFold(req.Schemas, datasetSchema(result.FullName)) {
+		return false
+	}
+	if req.OwnerScope != nil && (result.OwnerScope == nil || !strings.EqualFold(*req.OwnerScope, *result.OwnerScope)) {
+		return false
+	}
+	private := result.Visibility != nil && strings.EqualFold(*result.Visibility, "private")
+	if private && (req.IncludePrivate == nil || !*req.IncludePrivate) {
+		return false
+	}
+	return true
+}
+
+// datasetSchema returns the schema of a full name: dex for dex.trades and dune.dex.trades
+func datasetSchema(fullName string) string {
+	parts := strings.Split(fullName, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
+
+// searchTexts returns the search text of each result
+func searchTexts(results []models.SearchDatasetResult) []string {
+	texts := make([]string, len(results))
+	for i, result := range results {
+		texts[i] = searchText(result)
+	}
+	return texts
+}
+
+// searchText returns the lower cased text a query is matched against
+func searchText(result models.SearchDatasetResult) string {
+	parts := []string{result.FullName}
+	parts = append(parts, result.Blockchains...)
+	if result.Description != nil {
+		parts = append(parts, *result.Description)
+	}
+	if m := result.Metadata; m != nil {
+		for _, s := range []*string{m.Description, m.ProjectName, m.ContractName} {
+			if s != nil {
+				parts = append(parts, *s)
+			}
+		}
+	}
+	// results with a malformed schema are matched on their other fields
+	if schema, err := result.DecodeSchema(); err == nil && schema != nil {
+		for _, column := range schema.Columns {
+			parts = append(parts, column.Name)
+		}
+	}
+	return strings.ToLower(strings.Join(parts, "\n"))
+}
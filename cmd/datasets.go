@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/duneanalytics/duneapi-client-go/catalog"
+)
+
+const datasetsUsage = `usage: dunecli datasets <command> [flags]
+
+  diff [-snapshot file] [-format text|json] [-exit-code] <dataset> [<dataset>...]
+         report the column changes of datasets since the previous run, and save the current columns`
+
+func runDatasets(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, datasetsUsage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "diff":
+		runDatasetsDiff(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, datasetsUsage)
+		os.Exit(1)
+	}
+}
+
+func runDatasetsDiff(args []string) {
+	flags := flag.NewFlagSet("datasets diff", flag.ExitOnError)
+	snapshotPath := flags.String("snapshot", "datasets.snapshot.json", "File holding the columns seen at the previous run")
+	format := flags.String("format", "text", "Output format: text or json")
+	exitCode := flags.Bool("exit-code", false, "Exit with status 2 when changes are found")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, datasetsUsage)
+		os.Exit(1)
+	}
+
+	watcher := catalog.NewSchemaWatcher(newClientOrExit(), *snapshotPath, flags.Args())
+	report, err := watcher.Check(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to check datasets:", err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to encode report as json:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	default:
+		fmt.Print(report.String())
+	}
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+	if *exitCode && report.HasChanges() {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,80 @@
+// Command dunegen generates Go structs for the rows of Dune datasets, for use with dune.DecodeRows,
+// and typed wrappers for saved queries.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/codegen"
+	"github.com/duneanalytics/duneapi-client-go/config"
+	"github.com/duneanalytics/duneapi-client-go/dune"
+)
+
+const usage = `usage: dunegen [-pkg name] [-o file] <dataset> [<dataset>...]
+       dunegen [-pkg name] [-o file] [-poll interval] [-retries n] -q <query_id>[,<query_id>...]
+
+Generates a Go struct for each dataset, such as dex.trades, with a field per column,
+or a params struct, a row struct and a Run function for each saved query.`
+
+func main() {
+	pkg := flag.String("pkg", "tables", "Package name of the generated file")
+	output := flag.String("o", "", "Output file, stdout if empty")
+	queryIDsStr := flag.String("q", "", "Comma separated IDs of saved queries to generate wrappers for")
+	pollInterval := flag.Duration("poll", codegen.DefaultSamplePollInterval,
+		"Delay between two result requests when a query without results is executed")
+	maxRetries := flag.Int("retries", codegen.DefaultSampleMaxRetries,
+		"Number of failed result requests tolerated when a query without results is executed")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if (flag.NArg() == 0) == (*queryIDsStr == "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	env, err := config.FromEnvVars()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	client := dune.NewDuneClient(env)
+
+	var src []byte
+	if *queryIDsStr != "" {
+		var queryIDs []int
+		for _, s := range strings.Split(*queryIDsStr, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid query ID %q\n", s)
+				os.Exit(1)
+			}
+			queryIDs = append(queryIDs, id)
+		}
+		src, err = codegen.Queries(client, *pkg, queryIDs, codegen.SampleOptions{
+			PollInterval: *pollInterval,
+			MaxRetries:   *maxRetries,
+		})
+	} else {
+		src, err = codegen.Datasets(client, *pkg, flag.Args())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to generate code:", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
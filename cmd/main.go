@@ -12,7 +12,23 @@ import (
 	"github.com/duneanalytics/duneapi-client-go/models"
 )
 
+// commands maps the CLI subcommands to their entry points. When the first argument is not
+// a known subcommand, the CLI falls back to running a query or checking an execution.
+var commands = map[string]func(args []string){
+	"datasets": runDatasets,
+	"sync":     runSync,
+	"uploads":  runUploads,
+	"usage":    runUsage,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	queryID := flag.Int("q", 0, "The ID of the query to execute. Conflicts with -e")
 	queryParametersStr := flag.String("p", "{}", "Parameters to pass to the query in JSON format")
 	executionID := flag.String("e", "", "ID of an existing execution to check status. Conflicts with -q")
@@ -28,16 +44,11 @@ func main() {
 	}
 
 	// Load the API key config from the DUNE_API_KEY environment variable
-	env, err := config.FromEnvVars()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
-	client := dune.NewDuneClient(env)
+	client := newClientOrExit()
 	var execution dune.Execution
 
 	var queryParameters map[string]any
-	err = json.Unmarshal([]byte(*queryParametersStr), &queryParameters)
+	err := json.Unmarshal([]byte(*queryParametersStr), &queryParameters)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to parse query parameters: %s\n", err.Error())
 	}
@@ -70,4 +81,27 @@ func main() {
 	}
 
 	fmt.Println(string(out))
+
+	// records the execution even if it did not finish in time
+	if err := client.FlushUsage(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record execution usage:", err)
+	}
+}
+
+// usageLogEnvVar names the file the CLI appends the usage of its executions to, when set
+const usageLogEnvVar = "DUNE_USAGE_LOG"
+
+// newClientOrExit builds a client from the environment, exiting when DUNE_API_KEY is not set.
+// The usage of its executions is recorded to the file named by DUNE_USAGE_LOG, if set.
+func newClientOrExit() dune.DuneClient {
+	env, err := config.FromEnvVars()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	client := dune.NewDuneClient(env)
+	if path := os.Getenv(usageLogEnvVar); path != "" {
+		return client.WithUsageRecorder(dune.NewFileUsageRecorder(path))
+	}
+	return client
 }
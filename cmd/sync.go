@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/querysync"
+)
+
+const syncUsage = `usage: dunecli sync <push|pull|diff> [flags]
+
+  diff   show what push would change on Dune
+  push   create, update and archive saved queries to match the directory
+  pull   overwrite local files with the saved queries on Dune`
+
+func runSync(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, syncUsage)
+		os.Exit(1)
+	}
+	subcommand := args[0]
+
+	flags := flag.NewFlagSet("sync "+subcommand, flag.ExitOnError)
+	dir := flags.String("dir", ".", "Directory holding the .sql files and their sidecars")
+	queryIDsStr := flags.String("q", "", "Comma separated IDs of additional queries to pull")
+	format := flags.String("format", "text", "Output format of diff: text or json")
+	flags.Parse(args[1:])
+
+	syncer := querysync.New(newClientOrExit(), *dir)
+
+	switch subcommand {
+	case "diff":
+		changes, err := syncer.Plan()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to compute diff:", err)
+			os.Exit(1)
+		}
+		if err := printDiffs(changes, *format); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to render diff:", err)
+			os.Exit(1)
+		}
+	case "push":
+		changes, err := syncer.Push()
+		printChanges(changes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to push:", err)
+			os.Exit(1)
+		}
+	case "pull":
+		queryIDs, err := parseQueryIDs(*queryIDsStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		written, err := syncer.Pull(queryIDs...)
+		for _, path := range written {
+			fmt.Println("pulled", path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to pull:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, syncUsage)
+		os.Exit(1)
+	}
+}
+
+func printChanges(changes []querysync.Change) {
+	for _, change := range changes {
+		if change.Action == querysync.ActionNone {
+			continue
+		}
+		line := fmt.Sprintf("%-9s %s", change.Action, change.Local.SQLPath)
+		if change.Local.Meta.QueryID != 0 {
+			line += fmt.Sprintf(" (query %d)", change.Local.Meta.QueryID)
+		}
+		if len(change.Fields) > 0 {
+			line += ": " + strings.Join(change.Fields, ", ")
+		}
+		fmt.Println(line)
+	}
+}
+
+// printDiffs prints the field by field diff of every query that push would change
+func printDiffs(changes []querysync.Change, format string) error {
+	if format == "json" {
+		diffs := []*dune.QueryDiff{}
+		for _, change := range changes {
+			if change.Diff != nil && change.Diff.HasChanges() {
+				diffs = append(diffs, change.Diff)
+			}
+		}
+		out, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Action == querysync.ActionCreate:
+			fmt.Printf("create %s\n", change.Local.SQLPath)
+		case change.Action == querysync.ActionArchive:
+			fmt.Printf("archive %s (query %d)\n", change.Local.SQLPath, change.Local.Meta.QueryID)
+		case change.Action == querysync.ActionMissing:
+			fmt.Printf("missing %s (query %d no longer exists)\n", change.Local.SQLPath, change.Local.Meta.QueryID)
+		case change.Action == querysync.ActionUnarchive, change.Action == querysync.ActionUpdate:
+			fmt.Printf("%s %s\n%s", change.Action, change.Local.SQLPath, change.Diff.Text())
+		}
+	}
+	return nil
+}
+
+func parseQueryIDs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid query ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+const uploadsUsage = `usage: dunecli uploads <command> [flags]
+
+  infer-schema [-rows N] [-format csv|ndjson] <file>
+         propose a table schema from the first rows of a CSV or NDJSON file
+  insert [-checkpoint file] [-chunk-bytes N] [-validate] [-format csv|ndjson] <namespace> <table> <file>
+         insert a CSV or NDJSON file into an existing table, resuming from the checkpoint if given`
+
+func runUploads(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, uploadsUsage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "infer-schema":
+		runInferSchema(args[1:])
+	case "insert":
+		runInsert(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, uploadsUsage)
+		os.Exit(1)
+	}
+}
+
+func runInferSchema(args []string) {
+	flags := flag.NewFlagSet("uploads infer-schema", flag.ExitOnError)
+	sampleRows := flags.Int("rows", dune.DefaultInferSampleRows, "Number of rows to sample")
+	format := flags.String("format", "", "Input format, csv or ndjson. Guessed from the file extension if empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, uploadsUsage)
+		os.Exit(1)
+	}
+	path := flags.Arg(0)
+
+	contentType, err := contentTypeOf(path, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	schema, err := dune.InferSchema(f, contentType, *sampleRows)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to infer schema of %s: %s\n", path, err)
+		os.Exit(1)
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to encode schema as json:", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(out))
+}
+
+func runInsert(args []string) {
+	flags := flag.NewFlagSet("uploads insert", flag.ExitOnError)
+	checkpointPath := flags.String("checkpoint", "", "File recording the chunks written, to resume an interrupted insert")
+	chunkBytes := flags.Int("chunk-bytes", models.DefaultInsertChunkBytes, "Maximum size of each insert request")
+	validate := flags.Bool("validate", false, "Check rows against the table schema before sending them")
+	format := flags.String("format", "", "Input format, csv or ndjson. Guessed from the file extension if empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		fmt.Fprintln(os.Stderr, uploadsUsage)
+		os.Exit(1)
+	}
+	namespace, tableName, path := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+
+	contentType, err := contentTypeOf(path, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	client := newClientOrExit()
+	options := models.InsertOptions{MaxChunkBytes: *chunkBytes, Validate: *validate}
+	var resp *models.UploadsInsertResponse
+	if *checkpointPath != "" {
+		resp, err = client.InsertIntoUploadResumable(
+			context.Background(), namespace, tableName, f, contentType, *checkpointPath, options,
+		)
+	} else {
+		resp, err = client.InsertIntoUploadReader(context.Background(), namespace, tableName, f, contentType, options)
+	}
+	if resp != nil {
+		fmt.Printf("%d rows (%d bytes) written to %s.%s\n", resp.RowsWritten, resp.BytesWritten, namespace, tableName)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to insert %s: %s\n", path, err)
+		os.Exit(1)
+	}
+}
+
+// contentTypeOf returns the upload content type for an explicit format, or guesses it from the file extension
+func contentTypeOf(path, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case "csv":
+		return models.ContentTypeCSV, nil
+	case "ndjson", "jsonl", "json":
+		return models.ContentTypeNDJSON, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s, use -format csv or -format ndjson", path)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+)
+
+const usageUsage = `usage: dunecli usage <command> [flags]
+
+  report [-log file] [-by query|performance|day] [-format table|json|csv|records-json]
+         summarize the executions recorded in the usage log, which the CLI writes to when
+         DUNE_USAGE_LOG is set; csv and records-json export the recorded executions as is
+  forecast [-history file] [-offline] [-format table|json]
+         record a usage snapshot in the history file, then project the credits at the end of
+         the billing period and when the storage quota runs out from the trend of the snapshots`
+
+func runUsage(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usageUsage)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "report":
+		runUsageReport(args[1:])
+	case "forecast":
+		runUsageForecast(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usageUsage)
+		os.Exit(1)
+	}
+}
+
+func runUsageReport(args []string) {
+	flags := flag.NewFlagSet("usage report", flag.ExitOnError)
+	logPath := flags.String("log", os.Getenv(usageLogEnvVar), "Usage log to read, DUNE_USAGE_LOG by default")
+	by := flags.String("by", string(dune.UsageByQuery), "Group executions by query, performance or day")
+	format := flags.String("format", "table", "Output format: table, json, csv or records-json")
+	flags.Parse(args)
+
+	switch dune.UsageGroup(*by) {
+	case dune.UsageByQuery, dune.UsageByPerformance, dune.UsageByDay:
+	default:
+		fmt.Fprintln(os.Stderr, usageUsage)
+		os.Exit(1)
+	}
+	if *logPath == "" {
+		fmt.Fprintln(os.Stderr, "no usage log: set -log or DUNE_USAGE_LOG")
+		os.Exit(1)
+	}
+	f, err := os.Open(*logPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open usage log:", err)
+		os.Exit(1)
+	}
+	records, err := dune.ReadUsageRecords(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read usage log %s: %s\n", *logPath, err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "csv":
+		err = dune.WriteUsageCSV(os.Stdout, records)
+	case "records-json":
+		err = dune.WriteUsageJSON(os.Stdout, records)
+	case "json":
+		var out []byte
+		out, err = json.MarshalIndent(dune.SummarizeUsage(records, dune.UsageGroup(*by)), "", "  ")
+		fmt.Println(string(out))
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, *by+"\texecutions\tfailed\tunfinished\truntime (s)\tresult bytes\tdatapoints\tcredits\t")
+		for _, s := range dune.SummarizeUsage(records, dune.UsageGroup(*by)) {
+			credits := fmt.Sprintf("%.2f", s.Credits)
+			if s.UnknownCredits > 0 {
+				// the cost of some executions was not reported
+				credits = ">=" + credits
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f\t%d\t%d\t%s\t\n", s.Key, s.Executions, s.Failed, s.Unfinished,
+				s.RuntimeSeconds, s.ResultSetBytes, s.DatapointCount, credits)
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write report:", err)
+		os.Exit(1)
+	}
+}
+
+func runUsageForecast(args []string) {
+	flags := flag.NewFlagSet("usage forecast", flag.ExitOnError)
+	historyPath := flags.String("history", "usage.history.json", "File holding the usage snapshots")
+	offline := flags.Bool("offline", false, "Forecast from the saved snapshots without taking a new one")
+	format := flags.String("format", "table", "Output format: table or json")
+	flags.Parse(args)
+
+	history, err := dune.OpenUsageHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open usage history:", err)
+		os.Exit(1)
+	}
+	if !*offline {
+		if _, err := history.Record(newClientOrExit()); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to take a usage snapshot:", err)
+			os.Exit(1)
+		}
+	}
+	forecast, err := history.Forecast(time.Now().UTC())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to forecast usage:", err)
+		os.Exit(1)
+	}
+
+	if *format == "json" {
+		out, err := json.MarshalIndent(forecast, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to encode forecast as json:", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "billing period\t%s to %s\n", forecast.Period.StartDate, forecast.Period.EndDate)
+	fmt.Fprintf(w, "credits used\t%.2f of %d included\n", forecast.CreditsUsed, forecast.CreditsIncluded)
+	fmt.Fprintf(w, "burn rate\t%.2f credits/day (%s)\n", forecast.CreditsPerDay, forecast.BurnRateSource)
+	projected := fmt.Sprintf("%.2f credits", forecast.ProjectedCredits)
+	if forecast.CreditsIncluded > 0 {
+		projected += fmt.Sprintf(" (%.1f%% of included", forecast.ProjectedPercent)
+		if forecast.ProjectedOverage > 0 {
+			projected += fmt.Sprintf(", %.2f over", forecast.ProjectedOverage)
+		}
+		projected += ")"
+	}
+	fmt.Fprintf(w, "projected at period end\t%s\n", projected)
+	fmt.Fprintf(w, "storage\t%s of %s\n", formatBytes(float64(forecast.BytesUsed)),
+		formatBytes(float64(forecast.BytesAllowed)))
+	if forecast.BytesPerDay == nil {
+		fmt.Fprintf(w, "storage growth\tunknown until snapshots span an hour\n")
+	} else {
+		fmt.Fprintf(w, "storage growth\t%s/day\n", formatBytes(*forecast.BytesPerDay))
+	}
+	if exhaustedAt := forecast.StorageExhaustedAt; exhaustedAt != nil {
+		fmt.Fprintf(w, "storage full\t%s (in %.1f days)\n", exhaustedAt.Format(time.RFC3339),
+			max(exhaustedAt.Sub(forecast.GeneratedAt).Hours()/24, 0))
+	}
+	w.Flush()
+}
+
+// formatBytes renders a byte count with a binary unit, such as 1.5 GiB
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%s%.0f %s", sign, n, units[i])
+	}
+	return fmt.Sprintf("%s%.1f %s", sign, n, units[i])
+}
@@ -0,0 +1,199 @@
+// Package codegen generates Go row types from the schemas of Dune tables
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// initialisms are the words written in upper case in Go identifiers
+var initialisms = map[string]bool{
+	"id": true, "usd": true, "eth": true, "btc": true, "nft": true, "url": true, "uri": true,
+	"api": true, "abi": true, "evm": true, "json": true, "sql": true, "ip": true,
+}
+
+// Generator accumulates the types generated for a set of tables and the imports they need
+type Generator struct {
+	pkg     string
+	buf     bytes.Buffer
+	imports map[string]bool
+	names   map[string]bool
+}
+
+// New returns a generator writing to a file of package pkg
+func New(pkg string) *Generator {
+	return &Generator{
+		pkg:     pkg,
+		imports: map[string]bool{},
+		names:   map[string]bool{},
+	}
+}
+
+// Add generates a struct for the rows of table. The struct is named after the table, dex.trades
+// becoming DexTrades, and has a field per column tagged with the column name.
+func (g *Generator) Add(table models.TableInfo) error {
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("table %s has no columns", table.FullName)
+	}
+	name := uniqueName(g.names, GoName(table.FullName))
+
+	fmt.Fprintf(&g.buf, "\n// %s is a row of %s\n", name, table.FullName)
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	for _, column := range table.Columns {
+		if column.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(column.Description), "\n") {
+				fmt.Fprintf(&g.buf, "\t// %s\n", strings.TrimSpace(line))
+			}
+		}
+		field := uniqueName(fields, GoName(column.Name))
+		fmt.Fprintf(&g.buf, "\t%s %s `dune:%q`\n", field, g.goType(column.Type, column.Nullable), column.Name)
+	}
+	g.buf.WriteString("}\n")
+	return nil
+}
+
+// Source returns the gofmt-ed source of the generated file
+func (g *Generator) Source() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("// Code generated by dunegen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n", g.pkg)
+	if len(g.imports) > 0 {
+		// standard library packages first, then the others
+		var stdlib, others []string
+		for path := range g.imports {
+			if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+				others = append(others, path)
+			} else {
+				stdlib = append(stdlib, path)
+			}
+		}
+		slices.Sort(stdlib)
+		slices.Sort(others)
+		out.WriteString("\nimport (\n")
+		for _, path := range stdlib {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		if len(stdlib) > 0 && len(others) > 0 {
+			out.WriteString("\n")
+		}
+		for _, path := range others {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		out.WriteString(")\n")
+	}
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// goType returns the Go type holding values of a column type. Nullable scalars are pointers,
+// types that can already be nil (slices, maps, big numbers) are not.
+func (g *Generator) goType(t models.ParsedColumnType, nullable bool) string {
+	var typ string
+	switch {
+	case t.Type == models.ColumnTypeUint256 || t.Type == models.ColumnTypeInt256:
+		g.imports["math/big"] = true
+		return "*big.Int"
+	case t.Type == models.ColumnTypeDecimal && t.Scale == 0 && (t.Precision > 18 || t.Precision < 0):
+		// a decimal without precision is a decimal(38,0)
+		g.imports["math/big"] = true
+		return "*big.Int"
+	case t.Type == models.ColumnTypeDecimal && t.Scale > 0:
+		g.imports["math/big"] = true
+		return "*big.Float"
+	case t.Type.IsInteger() || t.Type == models.ColumnTypeDecimal:
+		typ = "int64"
+	case t.Type == models.ColumnTypeReal || t.Type == models.ColumnTypeDouble:
+		typ = "float64"
+	case t.Type == models.ColumnTypeBoolean:
+		typ = "bool"
+	case t.Type == models.ColumnTypeVarchar:
+		typ = "string"
+	case t.Type == models.ColumnTypeDate || t.Type == models.ColumnTypeTimestamp:
+		g.imports["time"] = true
+		typ = "time.Time"
+	case t.Type == models.ColumnTypeVarbinary:
+		return "[]byte"
+	case t.Type == models.ColumnTypeJSON:
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	case t.Type == models.ColumnTypeArray && t.Elem != nil:
+		return "[]" + g.goType(*t.Elem, false)
+	case t.Type == models.ColumnTypeMap && t.Key != nil && t.Elem != nil:
+		key := g.goType(*t.Key, false)
+		if key != "string" && key != "int64" && key != "bool" {
+			key = "string"
+		}
+		return "map[" + key + "]" + g.goType(*t.Elem, false)
+	default:
+		// rows and types without a Go equivalent
+		return "any"
+	}
+	if nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// GoName converts a table or column name to an exported Go identifier:
+// dex.trades -> DexTrades, amount_usd -> AmountUSD, tx_hash -> TxHash.
+func GoName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		lower := strings.ToLower(word)
+		if initialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	ident := b.String()
+	if unicode.IsDigit(rune(ident[0])) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// uniqueName returns name, suffixed with a number if it is already taken, and marks it as taken
+func uniqueName(taken map[string]bool, name string) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// Datasets generates a file of package pkg with a struct for each dataset, fetched with GetDataset
+func Datasets(client dune.DuneClient, pkg string, slugs []string) ([]byte, error) {
+	g := New(pkg)
+	for _, slug := range slugs {
+		dataset, err := client.GetDataset(slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dataset %s: %w", slug, err)
+		}
+		table, err := dataset.TableInfo()
+		if err != nil {
+			return nil, err
+		}
+		if err := g.Add(table); err != nil {
+			return nil, err
+		}
+	}
+	return g.Source()
+}
@@ -0,0 +1,90 @@
+package codegen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/config"
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) dune.DuneClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return dune.NewDuneClient(&config.Env{
+		APIKey: "test-api-key",
+		Host:   server.URL,
+	})
+}
+
+func TestGoName(t *testing.T) {
+	require.Equal(t, "DexTrades", GoName("dex.trades"))
+	require.Equal(t, "AmountUSD", GoName("amount_usd"))
+	require.Equal(t, "TxHash", GoName("tx_hash"))
+	require.Equal(t, "X1inchSwaps", GoName("1inch_swaps"))
+	require.Equal(t, "X", GoName("__"))
+}
+
+func TestDatasets(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/datasets/dex.trades", r.URL.Path)
+		json.NewEncoder(w).Encode(models.DatasetResponse{
+			FullName: "dex.trades",
+			Type:     "spell",
+			Columns: []models.DatasetColumn{
+				{Name: "block_time", Type: "timestamp(3) with time zone"},
+				{Name: "amount_usd", Type: "double", Nullable: true, Description: "Value of the trade in USD"},
+				{Name: "token_bought_amount_raw", Type: "uint256", Nullable: true},
+				{Name: "tx_hash", Type: "varbinary"},
+				{Name: "project", Type: "varchar"},
+				{Name: "fee", Type: "decimal(38,18)"},
+				{Name: "supply", Type: "decimal"},
+				{Name: "trace_address", Type: "array(bigint)"},
+				{Name: "labels", Type: "map(varchar, varchar)"},
+				{Name: "Project", Type: "boolean"},
+				{Name: "details", Type: "row(a integer)"},
+			},
+		})
+	})
+
+	src, err := Datasets(client, "tables", []string{"dex.trades"})
+
+	require.NoError(t, err)
+	require.Equal(t, `// Code generated by dunegen. DO NOT EDIT.
+
+package tables
+
+import (
+	"math/big"
+	"time"
+)
+
+// DexTrades is a row of dex.trades
+type DexTrades struct {
+	BlockTime time.Time `+"`"+`dune:"block_time"`+"`"+`
+	// Value of the trade in USD
+	AmountUSD            *float64          `+"`"+`dune:"amount_usd"`+"`"+`
+	TokenBoughtAmountRaw *big.Int          `+"`"+`dune:"token_bought_amount_raw"`+"`"+`
+	TxHash               []byte            `+"`"+`dune:"tx_hash"`+"`"+`
+	Project              string            `+"`"+`dune:"project"`+"`"+`
+	Fee                  *big.Float        `+"`"+`dune:"fee"`+"`"+`
+	Supply               *big.Int          `+"`"+`dune:"supply"`+"`"+`
+	TraceAddress         []int64           `+"`"+`dune:"trace_address"`+"`"+`
+	Labels               map[string]string `+"`"+`dune:"labels"`+"`"+`
+	Project2             bool              `+"`"+`dune:"Project"`+"`"+`
+	Details              any               `+"`"+`dune:"details"`+"`"+`
+}
+`, string(src))
+}
+
+func TestDatasetsWithoutColumns(t *testing.T) {
+	g := New("tables")
+
+	err := g.Add(models.TableInfo{FullName: "dex.trades"})
+
+	require.ErrorContains(t, err, "dex.trades has no columns")
+}
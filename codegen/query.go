@@ -0,0 +1,257 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// DefaultSampleRows is the number of result rows sampled to type the columns of a query
+const DefaultSampleRows = 1000
+
+// parameterTimeLayout is the format of datetime query parameters
+const parameterTimeLayout = "2006-01-02 15:04:05"
+
+// AddQuery generates a typed wrapper for a saved query: a params struct with a field per query
+// parameter, a row struct typed after the sample result rows, and a Run function executing the
+// query and decoding its rows. Columns of the sample without a non-null value are typed any.
+func (g *Generator) AddQuery(query models.GetQueryResponse, sample models.Result) error {
+	if len(sample.Metadata.ColumnNames) == 0 {
+		return fmt.Errorf("query %d: the sample results have no columns", query.QueryID)
+	}
+	columns, err := sampleColumns(sample)
+	if err != nil {
+		return fmt.Errorf("query %d: %w", query.QueryID, err)
+	}
+
+	base := GoName(query.Name)
+	if query.Name == "" {
+		base = fmt.Sprintf("Query%d", query.QueryID)
+	}
+	base = uniqueName(g.names, base)
+	title := fmt.Sprintf("query %d", query.QueryID)
+	if query.Name != "" {
+		title += fmt.Sprintf(" (%s)", query.Name)
+	}
+	g.imports["context"] = true
+	g.imports["github.com/duneanalytics/duneapi-client-go/dune"] = true
+	g.imports["github.com/duneanalytics/duneapi-client-go/models"] = true
+
+	params := base + "Params"
+	if len(query.Parameters) > 0 {
+		g.writeParams(params, title, query.Parameters)
+	}
+
+	row := base + "Row"
+	fmt.Fprintf(&g.buf, "\n// %s is a result row of %s\n", row, title)
+	fmt.Fprintf(&g.buf, "type %s struct {\n", row)
+	fields := map[string]bool{}
+	for _, column := range columns {
+		field := uniqueName(fields, GoName(column.Name))
+		fmt.Fprintf(&g.buf, "\t%s %s `dune:%q`\n", field, g.goType(column.Type, column.Nullable), column.Name)
+	}
+	g.buf.WriteString("}\n")
+
+	fmt.Fprintf(&g.buf, "\n// Run%s executes %s and decodes its result rows\n", base, title)
+	if len(query.Parameters) == 0 {
+		fmt.Fprintf(&g.buf, "func Run%s(ctx context.Context, client dune.DuneClient) ([]%s, error) {\n", base, row)
+		fmt.Fprintf(&g.buf, "\treturn dune.RunQueryRows[%s](ctx, client, models.ExecuteRequest{QueryID: %d})\n}\n",
+			row, query.QueryID)
+		return nil
+	}
+	fmt.Fprintf(&g.buf, "func Run%s(ctx context.Context, client dune.DuneClient, params %s) ([]%s, error) {\n",
+		base, params, row)
+	fmt.Fprintf(&g.buf, "\treturn dune.RunQueryRows[%s](ctx, client, models.ExecuteRequest{\n", row)
+	fmt.Fprintf(&g.buf, "\t\tQueryID: %d,\n\t\tQueryParameters: map[string]any{\n", query.QueryID)
+	paramFields := map[string]bool{}
+	for _, p := range query.Parameters {
+		value := "params." + uniqueName(paramFields, GoName(p.Key))
+		if p.Type == "datetime" {
+			value += fmt.Sprintf(".UTC().Format(%q)", parameterTimeLayout)
+		}
+		fmt.Fprintf(&g.buf, "\t\t\t%q: %s,\n", p.Key, value)
+	}
+	g.buf.WriteString("\t\t},\n\t})\n}\n")
+	return nil
+}
+
+// writeParams writes the params struct of a query and a function returning its saved defaults
+func (g *Generator) writeParams(name, title string, parameters []models.QueryParameter) {
+	fmt.Fprintf(&g.buf, "\n// %s are the parameters of %s. All of them are sent on execution:\n", name, title)
+	fmt.Fprintf(&g.buf, "// start from Default%s to keep the defaults saved with the query.\n", name)
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	names := make([]string, len(parameters))
+	for i, p := range parameters {
+		names[i] = uniqueName(fields, GoName(p.Key))
+		if len(p.EnumOptions) > 0 {
+			fmt.Fprintf(&g.buf, "\t// %s is one of %s\n", names[i], strings.Join(p.EnumOptions, ", "))
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s\n", names[i], g.parameterType(p.Type))
+	}
+	g.buf.WriteString("}\n")
+
+	fmt.Fprintf(&g.buf, "\n// Default%s returns the default parameters saved with %s\n", name, title)
+	fmt.Fprintf(&g.buf, "func Default%s() %s {\n\treturn %s{\n", name, name, name)
+	for i, p := range parameters {
+		if value := parameterLiteral(p); value != "" {
+			fmt.Fprintf(&g.buf, "\t\t%s: %s,\n", names[i], value)
+		}
+	}
+	g.buf.WriteString("\t}\n}\n")
+}
+
+// parameterType returns the Go type of a query parameter type
+func (g *Generator) parameterType(typ string) string {
+	switch typ {
+	case "number":
+		return "float64"
+	case "datetime":
+		g.imports["time"] = true
+		return "time.Time"
+	}
+	// text and enum
+	return "string"
+}
+
+// parameterLiteral returns the Go literal of the default value of a parameter, or "" if it is empty or invalid
+func parameterLiteral(p models.QueryParameter) string {
+	switch p.Type {
+	case "number":
+		n, err := strconv.ParseFloat(strings.TrimSpace(p.Value), 64)
+		if err != nil || n == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	case "datetime":
+		t, err := time.Parse(parameterTimeLayout, strings.TrimSpace(p.Value))
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, 0, time.UTC)",
+			t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+	}
+	if p.Value == "" {
+		return ""
+	}
+	return strconv.Quote(p.Value)
+}
+
+// sampleColumns types the result columns from the values of the sample rows, in the order of the metadata
+func sampleColumns(sample models.Result) ([]models.TableColumn, error) {
+	var ndjson bytes.Buffer
+	encoder := json.NewEncoder(&ndjson)
+	for _, row := range sample.Rows {
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+	inferred := map[string]models.UploadsColumn{}
+	if len(sample.Rows) > 0 {
+		schema, err := dune.InferSchema(&ndjson, models.ContentTypeNDJSON, len(sample.Rows))
+		if err != nil {
+			return nil, fmt.Errorf("failed to type the sample rows: %w", err)
+		}
+		for _, column := range schema {
+			inferred[column.Name] = column
+		}
+	}
+
+	columns := make([]models.TableColumn, 0, len(sample.Metadata.ColumnNames))
+	for _, name := range sample.Metadata.ColumnNames {
+		column := models.TableColumn{Name: name, Type: models.ParsedColumnType{Precision: -1}}
+		if c, ok := inferred[name]; ok && !onlyNulls(sample.Rows, name) {
+			column.Type, _ = models.ParseColumnType(c.Type)
+			column.Nullable = c.Nullable
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// onlyNulls reports whether a column is null or missing in all rows
+func onlyNulls(rows []map[string]any, column string) bool {
+	for _, row := range rows {
+		if row[column] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SampleOptions sets how Queries waits for the executions of queries without results
+type SampleOptions struct {
+	// PollInterval is the delay between two result requests, DefaultSamplePollInterval if zero
+	PollInterval time.Duration
+	// MaxRetries is the number of failed result requests tolerated, DefaultSampleMaxRetries if zero
+	MaxRetries int
+}
+
+const (
+	// DefaultSamplePollInterval is the default delay between two result requests of a sample execution
+	DefaultSamplePollInterval = 5 * time.Second
+	// DefaultSampleMaxRetries is the default number of failed result requests of a sample execution
+	DefaultSampleMaxRetries = 10
+)
+
+// Queries generates a file of package pkg with a typed wrapper for each saved query. The result
+// columns are typed from the latest results of the query or, if it has none, from a new execution
+// with its default parameters, waited for as set by options.
+func Queries(client dune.DuneClient, pkg string, queryIDs []int, options SampleOptions) ([]byte, error) {
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultSamplePollInterval
+	}
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = DefaultSampleMaxRetries
+	}
+	g := New(pkg)
+	for _, queryID := range queryIDs {
+		query, err := client.GetQuery(queryID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get query %d: %w", queryID, err)
+		}
+		sample, err := sampleResults(client, queryID, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get results of query %d: %w", queryID, err)
+		}
+		if err := g.AddQuery(*query, sample); err != nil {
+			return nil, err
+		}
+	}
+	return g.Source()
+}
+
+// sampleResults returns the first rows of the latest results of a query, executing it only if it
+// has none
+func sampleResults(client dune.DuneClient, queryID int, options SampleOptions) (models.Result, error) {
+	latest, err := client.ResultsByQueryID(strconv.Itoa(queryID), models.ResultOptions{
+		Page: &models.ResultPageOption{Limit: DefaultSampleRows},
+	})
+	var httpErr *dune.HTTPError
+	switch {
+	case errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound:
+		// the query was never executed
+	case err != nil:
+		return models.Result{}, err
+	case len(latest.Result.Metadata.ColumnNames) > 0:
+		return latest.Result, nil
+	}
+
+	execution, err := client.RunQuery(models.ExecuteRequest{QueryID: queryID})
+	if err != nil {
+		return models.Result{}, err
+	}
+	results, err := execution.WaitGetResults(options.PollInterval, options.MaxRetries)
+	if err != nil {
+		return models.Result{}, err
+	}
+	return results.Result, nil
+}
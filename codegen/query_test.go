@@ -0,0 +1,175 @@
+package codegen
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueries(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query/1234":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{
+				QueryID: 1234,
+				Name:    "Top traders",
+				Parameters: []models.QueryParameter{
+					{Key: "days", Type: "number", Value: "7"},
+					{Key: "chain", Type: "enum", Value: "ethereum", EnumOptions: []string{"ethereum", "base"}},
+					{Key: "since", Type: "datetime", Value: "2024-01-01 00:00:00"},
+				},
+			})
+		case "/api/v1/query/1234/results":
+			require.Equal(t, "1000", r.URL.Query().Get("limit"))
+			ended := time.Now()
+			json.NewEncoder(w).Encode(models.ResultsResponse{
+				State:            "QUERY_STATE_COMPLETED",
+				ExecutionEndedAt: &ended,
+				Result: models.Result{
+					Metadata: models.ResultMetadata{
+						ColumnNames: []string{"trader", "volume_usd", "trades", "last_trade", "note"},
+						RowCount:    2,
+					},
+					Rows: []map[string]any{
+						{"trader": "0xabcd", "volume_usd": 10.5, "trades": 3, "last_trade": "2024-03-01 12:30:00.000 UTC"},
+						{"trader": "0x1234", "volume_usd": nil, "trades": 1, "last_trade": "2024-03-02 12:30:00.000 UTC"},
+					},
+				},
+			})
+		default:
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	src, err := Queries(client, "queries", []int{1234}, SampleOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, "// Code generated by dunegen. DO NOT EDIT.\n\n"+`package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// TopTradersParams are the parameters of query 1234 (Top traders). All of them are sent on execution:
+// start from DefaultTopTradersParams to keep the defaults saved with the query.
+type TopTradersParams struct {
+	Days float64
+	// Chain is one of ethereum, base
+	Chain string
+	Since time.Time
+}
+
+// DefaultTopTradersParams returns the default parameters saved with query 1234 (Top traders)
+func DefaultTopTradersParams() TopTradersParams {
+	return TopTradersParams{
+		Days:  7,
+		Chain: "ethereum",
+		Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// TopTradersRow is a result row of query 1234 (Top traders)
+type TopTradersRow struct {
+	Trader    []byte    `+"`dune:\"trader\"`"+`
+	VolumeUSD *float64  `+"`dune:\"volume_usd\"`"+`
+	Trades    int64     `+"`dune:\"trades\"`"+`
+	LastTrade time.Time `+"`dune:\"last_trade\"`"+`
+	Note      any       `+"`dune:\"note\"`"+`
+}
+
+// RunTopTraders executes query 1234 (Top traders) and decodes its result rows
+func RunTopTraders(ctx context.Context, client dune.DuneClient, params TopTradersParams) ([]TopTradersRow, error) {
+	return dune.RunQueryRows[TopTradersRow](ctx, client, models.ExecuteRequest{
+		QueryID: 1234,
+		QueryParameters: map[string]any{
+			"days":  params.Days,
+			"chain": params.Chain,
+			"since": params.Since.UTC().Format("2006-01-02 15:04:05"),
+		},
+	})
+}
+`, string(src))
+}
+
+func TestAddQueryWithoutParameters(t *testing.T) {
+	g := New("queries")
+
+	err := g.AddQuery(models.GetQueryResponse{QueryID: 7}, models.Result{
+		Metadata: models.ResultMetadata{ColumnNames: []string{"n"}},
+	})
+	require.NoError(t, err)
+	src, err := g.Source()
+
+	require.NoError(t, err)
+	require.Contains(t, string(src), "type Query7Row struct {\n\tN any `dune:\"n\"`\n}")
+	require.Contains(t, string(src),
+		"func RunQuery7(ctx context.Context, client dune.DuneClient) ([]Query7Row, error) {\n"+
+			"\treturn dune.RunQueryRows[Query7Row](ctx, client, models.ExecuteRequest{QueryID: 7})\n}")
+}
+
+func TestQueriesSampleExecution(t *testing.T) {
+	const sampleExecutionID = "01HXYZABCDEFGHJKMNPQRSTVWX"
+	for _, tc := range []struct {
+		name          string
+		latestStatus  int
+		expectedCalls []string
+		expectedErr   string
+	}{
+		{
+			name:         "executes a query without results",
+			latestStatus: http.StatusNotFound,
+			expectedCalls: []string{
+				"/api/v1/query/7",
+				"/api/v1/query/7/results",
+				"/api/v1/query/7/execute",
+				"/api/v1/execution/" + sampleExecutionID + "/results",
+			},
+		},
+		{
+			name:          "returns other errors",
+			latestStatus:  http.StatusInternalServerError,
+			expectedCalls: []string{"/api/v1/query/7", "/api/v1/query/7/results"},
+			expectedErr:   "failed to get results of query 7",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.URL.Path)
+				switch r.URL.Path {
+				case "/api/v1/query/7":
+					json.NewEncoder(w).Encode(models.GetQueryResponse{QueryID: 7})
+				case "/api/v1/query/7/results":
+					http.Error(w, `{"error": "no results"}`, tc.latestStatus)
+				case "/api/v1/query/7/execute":
+					json.NewEncoder(w).Encode(models.ExecuteResponse{ExecutionID: sampleExecutionID, State: "QUERY_STATE_PENDING"})
+				case "/api/v1/execution/" + sampleExecutionID + "/results":
+					ended := time.Now()
+					json.NewEncoder(w).Encode(models.ResultsResponse{
+						State:               "QUERY_STATE_COMPLETED",
+						IsExecutionFinished: true,
+						ExecutionEndedAt:    &ended,
+						Result:              models.Result{Metadata: models.ResultMetadata{ColumnNames: []string{"n"}}},
+					})
+				}
+			})
+
+			_, err := Queries(client, "queries", []int{7}, SampleOptions{PollInterval: time.Millisecond})
+
+			require.Equal(t, tc.expectedCalls, calls)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
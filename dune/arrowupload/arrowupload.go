@@ -0,0 +1,225 @@
+// Package arrowupload inserts Arrow record batches, Arrow IPC streams and Parquet files into Dune
+// tables, reading them with apache/arrow-go.
+//
+// The uploads API only accepts CSV and NDJSON, so records are adapted to dune.RecordBatch and sent
+// as NDJSON with dune.InsertRecordBatchReader, one batch at a time. Column types follow
+// dune.ArrowColumnType, Parquet files being mapped through the Arrow schema of their columns.
+// The package is separate from dune so that only the programs importing it depend on Arrow.
+package arrowupload
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// parquetBatchRows is the number of rows read from a Parquet file at a time
+const parquetBatchRows = 16 * 1024
+
+// Schema returns the columns of a table holding records of schema, to create it with CreateUpload
+func Schema(schema *arrow.Schema) []models.UploadsColumn {
+	columns := make([]models.UploadsColumn, schema.NumFields())
+	for i, field := range schema.Fields() {
+		columns[i] = models.UploadsColumn{
+			Name:     field.Name,
+			Type:     string(dune.ArrowColumnType(field.Type.String())),
+			Nullable: field.Nullable,
+		}
+	}
+	return columns
+}
+
+// recordBatch adapts an Arrow record to dune.RecordBatch
+type recordBatch struct {
+	record arrow.Record
+	schema []models.UploadsColumn
+}
+
+// Batch adapts an Arrow record to dune.RecordBatch. The record is not retained, it must stay valid
+// while the batch is used.
+func Batch(record arrow.Record) dune.RecordBatch {
+	return recordBatch{record: record, schema: Schema(record.Schema())}
+}
+
+func (b recordBatch) Schema() []models.UploadsColumn {
+	return append([]models.UploadsColumn{}, b.schema...)
+}
+
+func (b recordBatch) NumRows() int {
+	return int(b.record.NumRows())
+}
+
+func (b recordBatch) Value(column, row int) any {
+	return value(b.record.Column(column), row)
+}
+
+// value returns a value of an Arrow array in the form expected by dune.RecordBatch
+func value(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return a.Value(i)
+	case *array.Int16:
+		return a.Value(i)
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return a.Value(i)
+	case *array.Uint16:
+		return a.Value(i)
+	case *array.Uint32:
+		return a.Value(i)
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float16:
+		return a.Value(i).Float32()
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.StringView:
+		return a.Value(i)
+	case *array.Binary:
+		return a.Value(i)
+	case *array.LargeBinary:
+		return a.Value(i)
+	case *array.BinaryView:
+		return a.Value(i)
+	case *array.FixedSizeBinary:
+		return a.Value(i)
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.Decimal128:
+		// decimals with a scale are stored as double, see dune.ArrowColumnType
+		if scale := a.DataType().(*arrow.Decimal128Type).Scale; scale != 0 {
+			return a.Value(i).ToFloat64(scale)
+		}
+		return a.Value(i).BigInt()
+	case *array.Decimal256:
+		if scale := a.DataType().(*arrow.Decimal256Type).Scale; scale != 0 {
+			return a.Value(i).ToFloat64(scale)
+		}
+		return a.Value(i).BigInt()
+	}
+
+	// nested values and types without a Dune equivalent are stored as JSON text in varchar columns
+	switch v := arr.GetOneForMarshal(i).(type) {
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	default:
+		text, err := json.Marshal(v)
+		if err != nil {
+			return arr.ValueStr(i)
+		}
+		return string(text)
+	}
+}
+
+// recordReader adapts an Arrow record reader to dune.RecordBatchReader
+type recordReader struct {
+	records array.RecordReader
+}
+
+func (r recordReader) Next() (dune.RecordBatch, error) {
+	if r.records.Next() {
+		return Batch(r.records.Record()), nil
+	}
+	if err := r.records.Err(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// InsertRecords inserts the records of an Arrow record reader into an existing table, one record
+// at a time. It does not release records.
+func InsertRecords(
+	ctx context.Context, client dune.DuneClient, namespace, tableName string, records array.RecordReader,
+	options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	return dune.InsertRecordBatchReader(ctx, client, namespace, tableName, recordReader{records}, options)
+}
+
+// InsertIPCStream inserts the records of an Arrow IPC stream into an existing table
+func InsertIPCStream(
+	ctx context.Context, client dune.DuneClient, namespace, tableName string, r io.Reader,
+	options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	records, err := ipc.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Release()
+	return InsertRecords(ctx, client, namespace, tableName, records, options)
+}
+
+// InsertParquet inserts the rows of a Parquet file into an existing table. The file is read a batch
+// of rows at a time, so it is never held in memory. r is not closed.
+func InsertParquet(
+	ctx context.Context, client dune.DuneClient, namespace, tableName string, r parquet.ReaderAtSeeker,
+	options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	records, err := parquetRecords(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Release()
+	return InsertRecords(ctx, client, namespace, tableName, records, options)
+}
+
+// ParquetSchema returns the columns of a table holding the rows of a Parquet file, to create it
+// with CreateUpload
+func ParquetSchema(r parquet.ReaderAtSeeker) ([]models.UploadsColumn, error) {
+	reader, err := parquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := reader.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return Schema(schema), nil
+}
+
+func parquetReader(r parquet.ReaderAtSeeker) (*pqarrow.FileReader, error) {
+	parquetFile, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return pqarrow.NewFileReader(parquetFile, pqarrow.ArrowReadProperties{BatchSize: parquetBatchRows},
+		memory.DefaultAllocator)
+}
+
+func parquetRecords(ctx context.Context, r parquet.ReaderAtSeeker) (pqarrow.RecordReader, error) {
+	reader, err := parquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return reader.GetRecordReader(ctx, nil, nil)
+}
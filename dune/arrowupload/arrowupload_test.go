@@ -0,0 +1,138 @@
+package arrowupload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/duneanalytics/duneapi-client-go/config"
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "block_time", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}},
+	{Name: "hash", Type: &arrow.FixedSizeBinaryType{ByteWidth: 2}},
+	{Name: "value", Type: &arrow.Decimal128Type{Precision: 38, Scale: 0}, Nullable: true},
+	{Name: "price", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+	{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+}, nil)
+
+// the NDJSON rows expected for testRecord
+const testRows = `{"block_time":"2024-01-01 00:00:00.000","hash":"0xabcd","price":12.5,"tags":"[\"a\",\"b\"]",` +
+	`"value":1267650600228229401496703205376}
+{"block_time":"2024-01-02 00:00:00.000","hash":"0xef01","price":null,"tags":null,"value":null}
+`
+
+func testRecord(t *testing.T) arrow.Record {
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, testSchema)
+	defer builder.Release()
+
+	times := builder.Field(0).(*array.TimestampBuilder)
+	for _, day := range []int{1, 2} {
+		ts, err := arrow.TimestampFromTime(time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC), arrow.Microsecond)
+		require.NoError(t, err)
+		times.Append(ts)
+	}
+	builder.Field(1).(*array.FixedSizeBinaryBuilder).AppendValues([][]byte{{0xab, 0xcd}, {0xef, 0x01}}, nil)
+	values := builder.Field(2).(*array.Decimal128Builder)
+	values.Append(decimal128.FromBigInt(new(big.Int).Lsh(big.NewInt(1), 100)))
+	values.AppendNull()
+	prices := builder.Field(3).(*array.Decimal128Builder)
+	prices.Append(decimal128.FromI64(1250))
+	prices.AppendNull()
+	tags := builder.Field(4).(*array.ListBuilder)
+	tags.Append(true)
+	tags.ValueBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	tags.AppendNull()
+
+	record := builder.NewRecord()
+	t.Cleanup(record.Release)
+	return record
+}
+
+// newTestClient returns a client whose inserts are appended to body
+func newTestClient(t *testing.T, body *bytes.Buffer) dune.DuneClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/uploads/ns/t/insert", r.URL.Path)
+		require.Equal(t, models.ContentTypeNDJSON, r.Header.Get("Content-Type"))
+		data, _ := io.ReadAll(r.Body)
+		body.Write(data)
+		json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: int64(bytes.Count(data, []byte("\n")))})
+	}))
+	t.Cleanup(server.Close)
+	return dune.NewDuneClient(&config.Env{APIKey: "test-api-key", Host: server.URL})
+}
+
+func TestSchema(t *testing.T) {
+	require.Equal(t, []models.UploadsColumn{
+		{Name: "block_time", Type: "timestamp"},
+		{Name: "hash", Type: "varbinary"},
+		{Name: "value", Type: "int256", Nullable: true},
+		{Name: "price", Type: "double", Nullable: true},
+		{Name: "tags", Type: "varchar", Nullable: true},
+	}, Schema(testSchema))
+}
+
+func TestInsertRecords(t *testing.T) {
+	var body bytes.Buffer
+	client := newTestClient(t, &body)
+	record := testRecord(t)
+	records, err := array.NewRecordReader(testSchema, []arrow.Record{record, record})
+	require.NoError(t, err)
+	defer records.Release()
+
+	resp, err := InsertRecords(context.Background(), client, "ns", "t", records, models.InsertOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(4), resp.RowsWritten)
+	require.Equal(t, testRows+testRows, body.String())
+}
+
+func TestInsertIPCStream(t *testing.T) {
+	var stream bytes.Buffer
+	writer := ipc.NewWriter(&stream, ipc.WithSchema(testSchema))
+	require.NoError(t, writer.Write(testRecord(t)))
+	require.NoError(t, writer.Close())
+
+	var body bytes.Buffer
+	resp, err := InsertIPCStream(context.Background(), newTestClient(t, &body), "ns", "t", &stream,
+		models.InsertOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.RowsWritten)
+	require.Equal(t, testRows, body.String())
+}
+
+func TestInsertParquet(t *testing.T) {
+	var data bytes.Buffer
+	writer, err := pqarrow.NewFileWriter(testSchema, &data, nil, pqarrow.DefaultWriterProps())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(testRecord(t)))
+	require.NoError(t, writer.Close())
+
+	columns, err := ParquetSchema(bytes.NewReader(data.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, Schema(testSchema), columns)
+
+	var body bytes.Buffer
+	resp, err := InsertParquet(context.Background(), newTestClient(t, &body), "ns", "t",
+		bytes.NewReader(data.Bytes()), models.InsertOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.RowsWritten)
+	require.Equal(t, testRows, body.String())
+}
@@ -0,0 +1,152 @@
+package dune
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// ErrBudgetExceeded is matched by the *BudgetExceededError returned by budgeted clients
+var ErrBudgetExceeded = errors.New("credit budget exceeded")
+
+// BudgetExceededError is returned instead of starting an execution once the credits of the
+// current billing period crossed a threshold of BudgetOptions
+type BudgetExceededError struct {
+	Period models.BillingPeriod
+	// Limit is the threshold crossed, in credits
+	Limit float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: %.2f credits used of %.2f allowed for the period %s to %s",
+		ErrBudgetExceeded, e.Period.CreditsUsed, e.Limit, e.Period.StartDate, e.Period.EndDate)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// budgetGuard checks the usage of a client before executions, reusing it for the cache TTL
+type budgetGuard struct {
+	options models.BudgetOptions
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	usage     *models.UsageResponse
+}
+
+// WithBudget returns a client sharing the configuration of c that checks the usage of the current
+// billing period before QueryExecute, SQLExecute and QueryPipelineExecute, and the methods built on
+// them such as RunQuery and RunSQL, refusing them with a *BudgetExceededError once a threshold of
+// options is crossed. The usage is cached for options.CacheTTL, so a few executions may still start
+// after the threshold is crossed. When the usage cannot be fetched, or no billing period of it contains
+// the current time, executions are refused unless options.AllowOnUsageError is set.
+func (c *duneClient) WithBudget(options models.BudgetOptions) DuneClient {
+	if options.CacheTTL == 0 {
+		options.CacheTTL = models.DefaultBudgetCacheTTL
+	}
+	client := c.clone()
+	client.budget = &budgetGuard{options: options}
+	return client
+}
+
+// checkBudget returns an error if c has a budget that is exceeded
+func (c *duneClient) checkBudget() error {
+	if c.budget == nil {
+		return nil
+	}
+	return c.budget.check(c)
+}
+
+func (g *budgetGuard) check(c *duneClient) error {
+	usage, err := g.currentUsage(c)
+	if err != nil {
+		if g.options.AllowOnUsageError {
+			return nil
+		}
+		return fmt.Errorf("failed to check the credit budget: %w", err)
+	}
+
+	period, ok := CurrentBillingPeriod(usage, time.Now())
+	if !ok {
+		if g.options.AllowOnUsageError {
+			return nil
+		}
+		return errors.New("failed to check the credit budget: no billing period contains the current time")
+	}
+	limit := g.options.MaxCredits
+	if g.options.MaxPercent > 0 && period.CreditsIncluded > 0 {
+		percentLimit := float64(period.CreditsIncluded) * g.options.MaxPercent / 100
+		if limit == 0 || percentLimit < limit {
+			limit = percentLimit
+		}
+	}
+	if limit > 0 && period.CreditsUsed >= limit {
+		return &BudgetExceededError{Period: period, Limit: limit}
+	}
+	return nil
+}
+
+// currentUsage returns the cached usage, fetching it again once older than the cache TTL. The lock is
+// not held during the request, so checks racing on an expired cache may each fetch the usage.
+func (g *budgetGuard) currentUsage(c *duneClient) (*models.UsageResponse, error) {
+	g.mu.Lock()
+	usage, checkedAt := g.usage, g.checkedAt
+	g.mu.Unlock()
+	if usage != nil && time.Since(checkedAt) <= g.options.CacheTTL {
+		return usage, nil
+	}
+
+	usage, err := c.GetUsage()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	g.usage, g.checkedAt = usage, time.Now()
+	g.mu.Unlock()
+	return usage, nil
+}
+
+// billingDateLayouts are the formats of the dates of billing periods
+var billingDateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"}
+
+func parseBillingDate(s string) (time.Time, bool) {
+	for _, layout := range billingDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// billingPeriodBounds returns the start and the exclusive end of a billing period, or false when its
+// dates cannot be parsed. A date only end is inclusive, so the period ends at the start of the next day.
+func billingPeriodBounds(period models.BillingPeriod) (time.Time, time.Time, bool) {
+	start, okStart := parseBillingDate(period.StartDate)
+	end, okEnd := parseBillingDate(period.EndDate)
+	if len(period.EndDate) == len("2006-01-02") {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, okStart && okEnd
+}
+
+// CurrentBillingPeriod returns the billing period of usage containing now, the one ending last when
+// periods overlap. It returns false if no period contains now, such as when the dates cannot be parsed.
+func CurrentBillingPeriod(usage *models.UsageResponse, now time.Time) (models.BillingPeriod, bool) {
+	var current models.BillingPeriod
+	var currentEnd time.Time
+	found := false
+	for _, period := range usage.BillingPeriods {
+		start, end, ok := billingPeriodBounds(period)
+		if !ok || now.Before(start) || !now.Before(end) {
+			continue
+		}
+		if !found || end.After(currentEnd) {
+			current, currentEnd, found = period, end, true
+		}
+	}
+	return current, found
+}
@@ -0,0 +1,126 @@
+package dune
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+// pendingExecution is the response of the execute endpoints
+var pendingExecution = models.ExecuteResponse{ExecutionID: "01HKZJ2683PHF9Q9PHHQ8FW4Q1", State: "QUERY_STATE_PENDING"}
+
+func TestWithBudget(t *testing.T) {
+	today := time.Now().UTC()
+	creditsUsed := 0.0
+	var usageCalls, executions int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/usage":
+			usageCalls++
+			json.NewEncoder(w).Encode(models.UsageResponse{BillingPeriods: []models.BillingPeriod{
+				{StartDate: "2020-01-01", EndDate: "2020-01-31", CreditsUsed: 5000, CreditsIncluded: 1000},
+				{
+					StartDate:       today.AddDate(0, 0, -1).Format("2006-01-02"),
+					EndDate:         today.Format("2006-01-02"),
+					CreditsUsed:     creditsUsed,
+					CreditsIncluded: 1000,
+				},
+			}})
+		default:
+			executions++
+			json.NewEncoder(w).Encode(pendingExecution)
+		}
+	})
+
+	budgeted := client.WithBudget(models.BudgetOptions{MaxCredits: 900, MaxPercent: 80, CacheTTL: time.Hour})
+	_, err := budgeted.SQLExecute(models.ExecuteSQLRequest{SQL: "SELECT 1"})
+	require.NoError(t, err)
+	_, err = budgeted.QueryExecute(models.ExecuteRequest{QueryID: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, usageCalls)
+	require.Equal(t, 2, executions)
+
+	// the usage is cached: crossing the threshold is only seen by a new client
+	creditsUsed = 800
+	_, err = budgeted.RunSQL(models.ExecuteSQLRequest{SQL: "SELECT 1"})
+	require.NoError(t, err)
+	require.Equal(t, 1, usageCalls)
+
+	budgeted = client.WithBudget(models.BudgetOptions{MaxCredits: 900, MaxPercent: 80})
+	_, err = budgeted.QueryPipelineExecute(models.PipelineExecuteRequest{QueryID: "1"})
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	var exceeded *BudgetExceededError
+	require.True(t, errors.As(err, &exceeded))
+	require.Equal(t, 800.0, exceeded.Limit)
+	require.Equal(t, 800.0, exceeded.Period.CreditsUsed)
+	_, err = budgeted.RunQuery(models.ExecuteRequest{QueryID: 1})
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	require.Equal(t, 3, executions)
+
+	// the client WithBudget was called on is not budgeted
+	_, err = client.SQLExecute(models.ExecuteSQLRequest{SQL: "SELECT 1"})
+	require.NoError(t, err)
+}
+
+func TestWithBudgetUsageError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/usage" {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "internal error"})
+			return
+		}
+		json.NewEncoder(w).Encode(pendingExecution)
+	})
+
+	req := models.ExecuteSQLRequest{SQL: "SELECT 1"}
+	_, err := client.WithBudget(models.BudgetOptions{MaxCredits: 10}).SQLExecute(req)
+	require.ErrorContains(t, err, "failed to check the credit budget")
+	_, err = client.WithBudget(models.BudgetOptions{MaxCredits: 10, AllowOnUsageError: true}).SQLExecute(req)
+	require.NoError(t, err)
+}
+
+func TestWithBudgetNoCurrentPeriod(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/usage" {
+			json.NewEncoder(w).Encode(models.UsageResponse{BillingPeriods: []models.BillingPeriod{
+				{StartDate: "2020-01-01", EndDate: "2020-01-31", CreditsUsed: 5},
+				{StartDate: "yesterday", EndDate: "tomorrow", CreditsUsed: 5},
+			}})
+			return
+		}
+		json.NewEncoder(w).Encode(pendingExecution)
+	})
+
+	// without a period to compare the credits with, the budget fails closed
+	req := models.ExecuteSQLRequest{SQL: "SELECT 1"}
+	_, err := client.WithBudget(models.BudgetOptions{MaxCredits: 10}).SQLExecute(req)
+	require.EqualError(t, err, "failed to check the credit budget: no billing period contains the current time")
+	_, err = client.WithBudget(models.BudgetOptions{MaxCredits: 10, AllowOnUsageError: true}).SQLExecute(req)
+	require.NoError(t, err)
+}
+
+func TestCurrentBillingPeriod(t *testing.T) {
+	usage := &models.UsageResponse{BillingPeriods: []models.BillingPeriod{
+		{StartDate: "2024-01-01", EndDate: "2024-01-31", CreditsUsed: 1},
+		{StartDate: "2024-02-01", EndDate: "2024-02-29", CreditsUsed: 2},
+	}}
+	period, ok := CurrentBillingPeriod(usage, time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	require.Equal(t, 1.0, period.CreditsUsed)
+	_, ok = CurrentBillingPeriod(usage, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.False(t, ok)
+	_, ok = CurrentBillingPeriod(&models.UsageResponse{}, time.Now())
+	require.False(t, ok)
+
+	// the period ending last wins when periods overlap
+	usage.BillingPeriods = append(usage.BillingPeriods,
+		models.BillingPeriod{StartDate: "2024-02-15", EndDate: "2024-03-14", CreditsUsed: 3})
+	period, ok = CurrentBillingPeriod(usage, time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	require.Equal(t, 3.0, period.CreditsUsed)
+}
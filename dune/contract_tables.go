@@ -0,0 +1,205 @@
+package dune
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// ContractTableKind tells decoded event tables from decoded call tables
+type ContractTableKind string
+
+const (
+	ContractEvent ContractTableKind = "event"
+	ContractCall  ContractTableKind = "call"
+)
+
+var (
+	// decodedTablePattern matches the table part of decoded table names: Pair_evt_Swap, Pair_call_swap
+	decodedTablePattern = regexp.MustCompile(`^(.*)_(evt|call)_(.+)$`)
+	plainIdentifier     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ContractTable is a decoded event or call table of a contract, with what is needed to query it
+type ContractTable struct {
+	FullName   string
+	Blockchain string
+	Kind       ContractTableKind
+	// Contract is the contract name of the table, such as Pair for uniswap_v3_ethereum.Pair_evt_Swap
+	Contract string
+	// Name is the event or function name, such as Swap
+	Name string
+	// Address is the contract address, lower cased
+	Address string
+	// Schema is the schema returned by the search, nil if the API sent none
+	Schema *models.DatasetSchema
+	// TimeColumn is the block time column, evt_block_time or call_block_time
+	TimeColumn string
+}
+
+// ContractTables finds the decoded event and call tables of a contract with
+// SearchDatasetsByContractAddress, including their schemas. blockchain may be empty to search
+// all chains. Tables are returned in the order of the search, events first.
+func (c *duneClient) ContractTables(ctx context.Context, contractAddress, blockchain string) ([]ContractTable, error) {
+	address := strings.ToLower(strings.TrimSpace(contractAddress))
+	if address == "0x" || !hexPattern.MatchString(address) {
+		return nil, fmt.Errorf("invalid contract address %q, expected 0x prefixed hex", contractAddress)
+	}
+
+	includeSchema := true
+	req := models.SearchDatasetsByContractAddressRequest{ContractAddress: address, IncludeSchema: &includeSchema}
+	if blockchain != "" {
+		req.Blockchains = []string{blockchain}
+	}
+
+	var events, calls []ContractTable
+	for offset := int32(0); ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pageOffset := offset
+		req.Offset = &pageOffset
+		resp, err := c.SearchDatasetsByContractAddress(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range resp.Results {
+			table, ok, err := contractTable(result, address, blockchain)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case !ok:
+			case table.Kind == ContractEvent:
+				events = append(events, table)
+			default:
+				calls = append(calls, table)
+			}
+		}
+		if !resp.Pagination.HasMore || len(resp.Results) == 0 {
+			break
+		}
+		offset += int32(len(resp.Results))
+		if resp.Pagination.NextOffset != nil {
+			offset = *resp.Pagination.NextOffset
+		}
+	}
+	return append(events, calls...), nil
+}
+
+// contractTable describes a search result, or returns false if it is not a decoded event or call table
+func contractTable(result models.SearchDatasetResult, address, blockchain string) (ContractTable, bool, error) {
+	schemaName, tableName, ok := strings.Cut(result.FullName, ".")
+	if !ok {
+		return ContractTable{}, false, nil
+	}
+	match := decodedTablePattern.FindStringSubmatch(tableName)
+	if match == nil {
+		return ContractTable{}, false, nil
+	}
+
+	table := ContractTable{
+		FullName:   result.FullName,
+		Blockchain: blockchain,
+		Kind:       ContractEvent,
+		Contract:   match[1],
+		Name:       match[3],
+		Address:    address,
+		TimeColumn: "evt_block_time",
+	}
+	if match[2] == "call" {
+		table.Kind = ContractCall
+		table.TimeColumn = "call_block_time"
+	}
+	if table.Blockchain == "" {
+		if len(result.Blockchains) == 1 {
+			table.Blockchain = result.Blockchains[0]
+		} else if i := strings.LastIndex(schemaName, "_"); i >= 0 {
+			// decoded schemas end with the chain: uniswap_v3_ethereum
+			table.Blockchain = schemaName[i+1:]
+		}
+	}
+
+	schema, err := result.DecodeSchema()
+	if err != nil {
+		return ContractTable{}, false, err
+	}
+	table.Schema = schema
+	if !table.hasColumn(table.TimeColumn) {
+		// not a standard decoded table, fall back to its first timestamp column
+		for _, column := range schema.Columns {
+			if models.BaseColumnType(column.Type) == models.ColumnTypeTimestamp {
+				table.TimeColumn = column.Name
+				break
+			}
+		}
+	}
+	return table, true, nil
+}
+
+// tableSQL returns the name of a table for use in DuneSQL, quoting the parts that need it
+func tableSQL(fullName string) string {
+	parts := strings.Split(fullName, ".")
+	for i, part := range parts {
+		if !plainIdentifier.MatchString(part) {
+			parts[i] = quoteIdentifier(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// filters returns the WHERE conditions selecting the rows of the contract over the last days
+func (t ContractTable) filters(days int) string {
+	var conditions []string
+	if t.hasColumn("contract_address") {
+		conditions = append(conditions, "contract_address = "+t.Address)
+	}
+	if days > 0 {
+		conditions = append(conditions, fmt.Sprintf("%s > now() - interval '%d' day", t.TimeColumn, days))
+	}
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, "\n  AND ") + "\n"
+}
+
+// hasColumn reports whether the table has a column, assuming it does when the schema is unknown
+func (t ContractTable) hasColumn(name string) bool {
+	if t.Schema == nil || len(t.Schema.Columns) == 0 {
+		return true
+	}
+	_, ok := t.Schema.Column(name)
+	return ok
+}
+
+// RecentSQL returns DuneSQL selecting the latest limit rows of the contract over the last days,
+// newest first. A zero days or limit disables the bound. The SQL can be passed to RunSQL as is.
+func (t ContractTable) RecentSQL(days, limit int) string {
+	sql := fmt.Sprintf("SELECT *\nFROM %s\n%sORDER BY %s DESC\n", tableSQL(t.FullName), t.filters(days), t.TimeColumn)
+	if limit > 0 {
+		sql += fmt.Sprintf("LIMIT %d\n", limit)
+	}
+	return sql
+}
+
+// DailyCountSQL returns DuneSQL counting the rows of the contract per day over the last days.
+// Calls are restricted to successful ones when the table records it.
+func (t ContractTable) DailyCountSQL(days int) string {
+	filters := t.filters(days)
+	countName := "events"
+	if t.Kind == ContractCall {
+		countName = "calls"
+		if t.hasColumn("call_success") {
+			if filters == "" {
+				filters = "WHERE call_success\n"
+			} else {
+				filters += "  AND call_success\n"
+			}
+		}
+	}
+	return fmt.Sprintf("SELECT date_trunc('day', %s) AS day, count(*) AS %s\nFROM %s\n%sGROUP BY 1\nORDER BY 1\n",
+		t.TimeColumn, countName, tableSQL(t.FullName), filters)
+}
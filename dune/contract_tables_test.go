@@ -0,0 +1,103 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractTables(t *testing.T) {
+	var requests []models.SearchDatasetsByContractAddressRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/datasets/search-by-contract", r.URL.Path)
+		var req models.SearchDatasetsByContractAddressRequest
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &req))
+		requests = append(requests, req)
+		if *req.Offset == 0 {
+			next := int32(2)
+			json.NewEncoder(w).Encode(models.SearchDatasetsResponse{
+				Results: []models.SearchDatasetResult{
+					{
+						FullName:    "uniswap_v3_ethereum.Pair_call_swap",
+						Blockchains: []string{"ethereum"},
+						Schema: json.RawMessage(`[{"name": "call_success", "type": "boolean"},
+							{"name": "call_block_time", "type": "timestamp(3) with time zone"}]`),
+					},
+					{FullName: "uniswap_v3_ethereum.pools"},
+				},
+				Pagination: models.SearchDatasetsPagination{HasMore: true, NextOffset: &next},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(models.SearchDatasetsResponse{
+			Results: []models.SearchDatasetResult{{
+				FullName: "uniswap_v3_ethereum.Pair_evt_Swap",
+				Schema: json.RawMessage(`[{"name": "contract_address", "type": "varbinary"},
+					{"name": "evt_block_time", "type": "timestamp(3) with time zone"}]`),
+			}},
+		})
+	})
+
+	tables, err := client.ContractTables(context.Background(), "0x88E6A0c2dDD26FEEb64F039a2c41296FcB3f5640", "")
+
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	require.Equal(t, "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640", requests[0].ContractAddress)
+	require.True(t, *requests[0].IncludeSchema)
+	require.Nil(t, requests[0].Blockchains)
+	require.Len(t, tables, 2)
+
+	event := tables[0]
+	require.Equal(t, ContractEvent, event.Kind)
+	require.Equal(t, "Pair", event.Contract)
+	require.Equal(t, "Swap", event.Name)
+	require.Equal(t, "ethereum", event.Blockchain)
+	require.Equal(t, "evt_block_time", event.TimeColumn)
+	require.Equal(t, `SELECT *
+FROM uniswap_v3_ethereum.Pair_evt_Swap
+WHERE contract_address = 0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640
+  AND evt_block_time > now() - interval '7' day
+ORDER BY evt_block_time DESC
+LIMIT 100
+`, event.RecentSQL(7, 100))
+	require.Equal(t, `SELECT date_trunc('day', evt_block_time) AS day, count(*) AS events
+FROM uniswap_v3_ethereum.Pair_evt_Swap
+WHERE contract_address = 0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640
+  AND evt_block_time > now() - interval '30' day
+GROUP BY 1
+ORDER BY 1
+`, event.DailyCountSQL(30))
+
+	call := tables[1]
+	require.Equal(t, ContractCall, call.Kind)
+	require.Equal(t, "swap", call.Name)
+	require.Equal(t, "call_block_time", call.TimeColumn)
+	// the call schema has no contract_address column
+	require.Equal(t, `SELECT date_trunc('day', call_block_time) AS day, count(*) AS calls
+FROM uniswap_v3_ethereum.Pair_call_swap
+WHERE call_success
+GROUP BY 1
+ORDER BY 1
+`, call.DailyCountSQL(0))
+}
+
+func TestContractTablesInvalidAddress(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected request")
+	})
+
+	_, err := client.ContractTables(context.Background(), "uniswap", "ethereum")
+
+	require.ErrorContains(t, err, `invalid contract address "uniswap"`)
+}
+
+func TestTableSQL(t *testing.T) {
+	require.Equal(t, "dex.trades", tableSQL("dex.trades"))
+	require.Equal(t, `my_project."1inch-swaps"`, tableSQL("my_project.1inch-swaps"))
+}
@@ -2,6 +2,7 @@ package dune
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -45,9 +46,14 @@ func (c *duneClient) ListDatasets(
 }
 
 func (c *duneClient) GetDataset(slug string) (*models.DatasetResponse, error) {
+	return c.getDataset(context.Background(), slug)
+}
+
+// getDataset is GetDataset with a context that cancels the request
+func (c *duneClient) getDataset(ctx context.Context, slug string) (*models.DatasetResponse, error) {
 	getURL := fmt.Sprintf(getDatasetURLTemplate, c.env.Host, slug)
 
-	req, err := http.NewRequest("GET", getURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", getURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,264 @@
+package dune
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+var (
+	bigFloatType = reflect.TypeOf(big.Float{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// DecodeRows decodes result rows, as returned in ResultsResponse.Result.Rows, into values of the
+// struct type T. Columns map to fields with the same dune tags as UploadStructs, so the structs
+// generated by dunegen can be used directly. Dune timestamps, 0x-prefixed varbinary, and large
+// numbers sent as strings are converted to time.Time, []byte and *big.Int fields. Columns without
+// a field are ignored, null values leave fields at their zero value.
+func DecodeRows[T any](rows []map[string]any) ([]T, error) {
+	fields, err := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]T, len(rows))
+	for i, row := range rows {
+		v := reflect.ValueOf(&out[i]).Elem()
+		for _, field := range fields {
+			value, ok := row[field.column.Name]
+			if !ok {
+				continue
+			}
+			if err := decodeValue(v.FieldByIndex(field.index), value); err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %w", i, field.column.Name, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+// RunQueryRows executes a saved query, waits for it to complete and decodes all its result rows
+// with DecodeRows. The execution is cancelled when ctx is done. It backs the query wrappers
+// generated by dunegen.
+func RunQueryRows[T any](ctx context.Context, client DuneClient, req models.ExecuteRequest) ([]T, error) {
+	execution, err := client.RunQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitExecution(ctx, client, execution); err != nil {
+		return nil, err
+	}
+	results, err := client.QueryResultsV2(execution.GetID(), models.ResultOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRows[T](results.Result.Rows)
+}
+
+// decodeValue stores a value decoded from JSON results into dst
+func decodeValue(dst reflect.Value, value any) error {
+	if value == nil {
+		dst.SetZero()
+		return nil
+	}
+
+	t := dst.Type()
+	switch {
+	case t == rawJSONType:
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		dst.SetBytes(raw)
+		return nil
+	case t.Kind() == reflect.Pointer:
+		elem := reflect.New(t.Elem())
+		if err := decodeValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case t == timeType:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into time.Time", value)
+		}
+		for _, layout := range timestampLayouts {
+			if ts, err := time.Parse(layout, s); err == nil {
+				dst.Set(reflect.ValueOf(ts))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid timestamp %q", s)
+	case t == bigIntType:
+		n, ok := parseBigInt(numberText(value))
+		if !ok {
+			return fmt.Errorf("invalid integer %v", value)
+		}
+		dst.Set(reflect.ValueOf(n).Elem())
+		return nil
+	case t == bigFloatType:
+		n, ok := new(big.Float).SetString(numberText(value))
+		if !ok {
+			return fmt.Errorf("invalid number %v", value)
+		}
+		dst.Set(reflect.ValueOf(n).Elem())
+		return nil
+	case t == bytesType:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into []byte", value)
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+		if err != nil {
+			return fmt.Errorf("invalid varbinary %q: %w", s, err)
+		}
+		dst.SetBytes(b)
+		return nil
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into %s", value, t)
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+		if err != nil {
+			return fmt.Errorf("invalid varbinary %q: %w", s, err)
+		}
+		if len(b) != t.Len() {
+			return fmt.Errorf("cannot decode %d bytes into %s", len(b), t)
+		}
+		reflect.Copy(dst, reflect.ValueOf(b))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(value))
+		return nil
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			dst.SetString(s)
+			return nil
+		}
+		dst.SetString(jsonText(value))
+		return nil
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			dst.SetBool(v)
+			return nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(numberText(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %v: %w", value, err)
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, t)
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(numberText(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %v: %w", value, err)
+		}
+		if dst.OverflowUint(n) {
+			return fmt.Errorf("%d overflows %s", n, t)
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(numberText(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %v: %w", value, err)
+		}
+		dst.SetFloat(n)
+		return nil
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into %s", value, t)
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(slice.Index(i), item); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case reflect.Map:
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into %s", value, t)
+		}
+		m := reflect.MakeMapWithSize(t, len(entries))
+		for k, item := range entries {
+			key := reflect.New(t.Key()).Elem()
+			if err := decodeValue(key, k); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(elem, item); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			m.SetMapIndex(key, elem)
+		}
+		dst.Set(m)
+		return nil
+	case reflect.Struct:
+		// rows and other nested values go through their JSON encoding
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(raw, dst.Addr().Interface())
+	}
+	return fmt.Errorf("cannot decode %T into %s", value, t)
+}
+
+// parseBigInt parses a decimal integer, or a hexadecimal one prefixed with 0x. Other prefixes are
+// not accepted, so that a decimal with leading zeros is not read as octal.
+func parseBigInt(s string) (*big.Int, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	if hexDigits, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		return new(big.Int).SetString(sign+hexDigits, 16)
+	}
+	return new(big.Int).SetString(sign+s, 10)
+}
+
+// numberText returns the text of a number decoded from JSON. Whole float64 values are printed
+// without exponent so they parse as integers.
+func numberText(value any) string {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e21 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return strings.TrimSpace(v)
+	}
+	return jsonText(value)
+}
@@ -0,0 +1,171 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+type decodedTrade struct {
+	BlockTime time.Time       `dune:"block_time"`
+	AmountUSD *float64        `dune:"amount_usd"`
+	AmountRaw *big.Int        `dune:"amount_raw"`
+	Fee       *big.Float      `dune:"fee"`
+	TxHash    []byte          `dune:"tx_hash"`
+	Project   string          `dune:"project"`
+	BlockNum  int64           `dune:"block_number"`
+	Trace     []int64         `dune:"trace_address"`
+	Labels    map[string]int  `dune:"labels"`
+	Extra     json.RawMessage `dune:"extra"`
+	Details   tradeMeta       `dune:"details"`
+	Flag      bool
+}
+
+func TestDecodeRows(t *testing.T) {
+	var rows []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`[{
+		"block_time": "2024-03-01 12:30:00.000 UTC",
+		"amount_usd": 12.5,
+		"amount_raw": "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+		"fee": "0.000000000000000001",
+		"tx_hash": "0xabcd",
+		"project": "uniswap",
+		"block_number": 19000000,
+		"trace_address": [0, 1],
+		"labels": {"a": 1},
+		"extra": {"k": [1, 2]},
+		"details": {"source": "api"},
+		"flag": true,
+		"unknown": "ignored"
+	}, {
+		"block_time": "2024-03-01T12:30:00Z",
+		"amount_usd": null,
+		"amount_raw": 1000,
+		"block_number": "7",
+		"tx_hash": null
+	}]`), &rows))
+
+	trades, err := DecodeRows[decodedTrade](rows)
+
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	first := trades[0]
+	require.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), first.BlockTime.UTC())
+	require.Equal(t, 12.5, *first.AmountUSD)
+	max256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	require.Equal(t, 0, max256.Cmp(first.AmountRaw))
+	require.Equal(t, "1e-18", first.Fee.Text('g', 10))
+	require.Equal(t, []byte{0xab, 0xcd}, first.TxHash)
+	require.Equal(t, "uniswap", first.Project)
+	require.Equal(t, int64(19000000), first.BlockNum)
+	require.Equal(t, []int64{0, 1}, first.Trace)
+	require.Equal(t, map[string]int{"a": 1}, first.Labels)
+	require.JSONEq(t, `{"k": [1, 2]}`, string(first.Extra))
+	require.Equal(t, tradeMeta{Source: "api"}, first.Details)
+	require.True(t, first.Flag)
+
+	second := trades[1]
+	require.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), second.BlockTime)
+	require.Nil(t, second.AmountUSD)
+	require.Equal(t, int64(1000), second.AmountRaw.Int64())
+	require.Equal(t, int64(7), second.BlockNum)
+	require.Nil(t, second.TxHash)
+}
+
+func TestDecodeRowsErrors(t *testing.T) {
+	_, err := DecodeRows[decodedTrade]([]map[string]any{{"block_number": 1.5}})
+	require.ErrorContains(t, err, "row 0, column block_number: invalid integer 1.5")
+
+	_, err = DecodeRows[decodedTrade]([]map[string]any{{"block_time": "yesterday"}})
+	require.ErrorContains(t, err, `invalid timestamp "yesterday"`)
+
+	_, err = DecodeRows[struct {
+		Pool [4]byte
+	}]([]map[string]any{{"pool": "0xabcd"}})
+	require.ErrorContains(t, err, "cannot decode 2 bytes into [4]uint8")
+
+	_, err = DecodeRows[int]([]map[string]any{{}})
+	require.ErrorContains(t, err, "expected a struct type")
+}
+
+func TestDecodeRowsBigInt(t *testing.T) {
+	rows, err := DecodeRows[struct {
+		Amount *big.Int
+	}]([]map[string]any{{"amount": "010"}, {"amount": "0x1F"}, {"amount": "-0x10"}, {"amount": float64(7)}})
+	require.NoError(t, err)
+	var amounts []string
+	for _, row := range rows {
+		amounts = append(amounts, row.Amount.String())
+	}
+	// leading zeros are decimal, not octal
+	require.Equal(t, []string{"10", "31", "-16", "7"}, amounts)
+
+	_, err = DecodeRows[struct {
+		Amount *big.Int
+	}]([]map[string]any{{"amount": "0b101"}})
+	require.ErrorContains(t, err, "invalid integer 0b101")
+}
+
+func TestDecodeRowsByteArray(t *testing.T) {
+	rows, err := DecodeRows[struct {
+		Pool [4]byte
+	}]([]map[string]any{{"pool": "0xdeadbeef"}})
+	require.NoError(t, err)
+	require.Equal(t, [4]byte{0xde, 0xad, 0xbe, 0xef}, rows[0].Pool)
+}
+
+func TestRunQueryRows(t *testing.T) {
+	executionID := "01HKZJ2683PHF9Q9PHHQ8FW4Q1"
+	var params map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ended := time.Now()
+		switch r.URL.Path {
+		case "/api/v1/query/42/execute":
+			var req models.ExecuteRequest
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &req)
+			params = req.QueryParameters
+			json.NewEncoder(w).Encode(models.ExecuteResponse{ExecutionID: executionID, State: "QUERY_STATE_PENDING"})
+		case "/api/v1/execution/" + executionID + "/status":
+			json.NewEncoder(w).Encode(models.StatusResponse{
+				ExecutionID:      executionID,
+				State:            "QUERY_STATE_COMPLETED",
+				ExecutionEndedAt: &ended,
+				ResultMetadata:   &models.ResultMetadata{},
+			})
+		case "/api/v1/execution/" + executionID + "/results":
+			json.NewEncoder(w).Encode(models.ResultsResponse{
+				State:            "QUERY_STATE_COMPLETED",
+				ExecutionEndedAt: &ended,
+				Result: models.Result{
+					Metadata: models.ResultMetadata{RowCount: 2},
+					Rows: []map[string]any{
+						{"project": "uniswap", "block_number": 1},
+						{"project": "curve", "block_number": 2},
+					},
+				},
+			})
+		default:
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	trades, err := RunQueryRows[decodedTrade](context.Background(), client, models.ExecuteRequest{
+		QueryID:         42,
+		QueryParameters: map[string]any{"days": 7},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"days": float64(7)}, params)
+	require.Equal(t, []decodedTrade{
+		{Project: "uniswap", BlockNum: 1},
+		{Project: "curve", BlockNum: 2},
+	}, trades)
+}
@@ -2,6 +2,7 @@ package dune
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,7 +15,9 @@ import (
 	"github.com/duneanalytics/duneapi-client-go/models"
 )
 
-// DuneClient represents all operations available to call externally
+// DuneClient represents all operations available to call externally.
+// Methods are added to it as the client supports new APIs, so implementations outside this
+// package, such as test mocks, should embed DuneClient and only override the methods they use.
 type DuneClient interface {
 	// New APIs to read results in a more flexible way
 	// returns the results or status of an execution, depending on whether it has completed
@@ -69,6 +72,16 @@ type DuneClient interface {
 	// GetUsageForDates returns usage statistics for a specified time range
 	GetUsageForDates(startDate, endDate string) (*models.UsageResponse, error)
 
+	// WithBudget returns a client refusing executions with a *BudgetExceededError once the credits
+	// of the current billing period cross a threshold
+	WithBudget(options models.BudgetOptions) DuneClient
+
+	// WithUsageRecorder returns a client recording the runtime and resources of the executions it launches
+	WithUsageRecorder(recorder UsageRecorder) DuneClient
+
+	// FlushUsage records the executions launched by a client with a usage recorder that were not seen finished
+	FlushUsage() error
+
 	// ListDatasets returns a paginated list of datasets with optional filtering
 	ListDatasets(limit, offset int, ownerHandle, datasetType string) (*models.ListDatasetsResponse, error)
 
@@ -93,6 +106,39 @@ type DuneClient interface {
 	// InsertIntoUpload inserts data into an existing table (CSV or NDJSON format)
 	InsertIntoUpload(namespace, tableName, data, contentType string) (*models.UploadsInsertResponse, error)
 
+	// InsertIntoUploadReader streams CSV or NDJSON data into an existing table, split in size capped chunks
+	InsertIntoUploadReader(
+		ctx context.Context, namespace, tableName string,
+		r io.Reader, contentType string, options models.InsertOptions,
+	) (*models.UploadsInsertResponse, error)
+
+	// InsertIntoUploadResumable streams data like InsertIntoUploadReader, checkpointing every written chunk
+	// to a local file so an interrupted upload resumes where it stopped
+	InsertIntoUploadResumable(
+		ctx context.Context, namespace, tableName string, r io.ReadSeeker, contentType, checkpointPath string,
+		options models.InsertOptions,
+	) (*models.UploadsInsertResponse, error)
+
+	// ReplaceUpload replaces the content of a table, loading and checking a staging table before touching it
+	ReplaceUpload(
+		ctx context.Context, namespace, tableName string, r io.ReadSeeker, contentType string,
+		options models.ReplaceOptions,
+	) (*models.UploadsInsertResponse, error)
+
+	// UpsertIntoUpload inserts data into a table, skipping or replacing the rows whose key is already in the table
+	UpsertIntoUpload(
+		ctx context.Context, namespace, tableName string, r io.Reader, contentType string,
+		options models.UpsertOptions,
+	) (*models.UpsertResponse, error)
+
+	// InsertRecordBatches inserts columnar batches, such as Arrow records adapted by arrowupload, into a table
+	InsertRecordBatches(
+		ctx context.Context, namespace, tableName string, batches []RecordBatch, options models.InsertOptions,
+	) (*models.UploadsInsertResponse, error)
+
+	// ValidateInsert checks CSV or NDJSON data against the schema of an existing table without sending it
+	ValidateInsert(namespace, tableName string, r io.Reader, contentType string) error
+
 	// DEPRECATED: Use ListUploads instead. Will be removed March 1, 2026.
 	ListTables(limit, offset int) (*models.UploadsListResponse, error)
 
@@ -120,18 +166,66 @@ type DuneClient interface {
 	// UpdateQuery updates an existing saved query
 	UpdateQuery(queryID int, req models.UpdateQueryRequest) (*models.UpdateQueryResponse, error)
 
+	// UpdateQueryIfVersion updates a saved query only if its version is still expectedVersion,
+	// returning a *QueryVersionConflictError otherwise
+	UpdateQueryIfVersion(
+		queryID, expectedVersion int, req models.UpdateQueryRequest,
+	) (*models.UpdateQueryResponse, error)
+
 	// ArchiveQuery archives a saved query
 	ArchiveQuery(queryID int) (*models.UpdateQueryResponse, error)
 
+	// UnarchiveQuery restores an archived saved query
+	UnarchiveQuery(queryID int) (*models.UpdateQueryResponse, error)
+
+	// MakeQueryPrivate makes a saved query visible only to its owner
+	MakeQueryPrivate(queryID int) (*models.UpdateQueryResponse, error)
+
+	// MakeQueryPublic makes a private saved query public
+	MakeQueryPublic(queryID int) (*models.UpdateQueryResponse, error)
+
+	// ListQueries returns a paginated list of saved queries with optional filtering
+	ListQueries(req models.ListQueriesRequest) (*models.ListQueriesResponse, error)
+
+	// WithTempQuery creates and executes a temporary query, passes the execution to fn
+	// and always archives the query afterwards
+	WithTempQuery(ctx context.Context, sql string, params map[string]any, fn func(Execution) error) error
+
+	// ArchiveStaleTempQueries archives the WithTempQuery queries of owner not updated for longer than olderThan
+	ArchiveStaleTempQueries(
+		ctx context.Context, owner string, olderThan time.Duration,
+	) ([]int, error)
+
 	// SearchDatasets searches for datasets across the catalog with advanced filters
 	SearchDatasets(req models.SearchDatasetsRequest) (*models.SearchDatasetsResponse, error)
 
 	// SearchDatasetsByContractAddress finds decoded datasets associated with a smart contract address
 	SearchDatasetsByContractAddress(req models.SearchDatasetsByContractAddressRequest) (*models.SearchDatasetsResponse, error)
+
+	// ContractTables finds the decoded event and call tables of a contract, with their schemas
+	// and starter SQL
+	ContractTables(ctx context.Context, contractAddress, blockchain string) ([]ContractTable, error)
+
+	// AllUploads iterates over all uploaded tables, fetching pages lazily
+	AllUploads(ctx context.Context, options models.PaginationOptions) *Iterator[models.UploadsListElement]
+
+	// AllDatasets iterates over all datasets matching the owner and type filters, fetching pages lazily
+	AllDatasets(
+		ctx context.Context, ownerHandle, datasetType string, options models.PaginationOptions,
+	) *Iterator[models.DatasetResponse]
+
+	// SearchAll iterates over all results of a dataset search, fetching pages lazily
+	SearchAll(
+		ctx context.Context, req models.SearchDatasetsRequest, options models.PaginationOptions,
+	) *Iterator[models.SearchDatasetResult]
 }
 
 type duneClient struct {
 	env *config.Env
+	// budget, if set, is checked before executions
+	budget *budgetGuard
+	// usage, if set, records the executions launched
+	usage *usageTracker
 }
 
 var (
@@ -163,6 +257,10 @@ var (
 	createQueryURLTemplate                     = "%s/api/v1/query"
 	queryURLTemplate                           = "%s/api/v1/query/%d"
 	archiveQueryURLTemplate                    = "%s/api/v1/query/%d/archive"
+	unarchiveQueryURLTemplate                  = "%s/api/v1/query/%d/unarchive"
+	privateQueryURLTemplate                    = "%s/api/v1/query/%d/private"
+	unprivateQueryURLTemplate                  = "%s/api/v1/query/%d/unprivate"
+	listQueriesURLTemplate                     = "%s/api/v1/queries"
 	searchDatasetsURLTemplate                  = "%s/api/v1/datasets/search"
 	searchDatasetsByContractAddressURLTemplate = "%s/api/v1/datasets/search-by-contract"
 )
@@ -238,6 +336,9 @@ func (c *duneClient) QueryCancel(executionID string) error {
 }
 
 func (c *duneClient) QueryExecute(req models.ExecuteRequest) (*models.ExecuteResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
 	executeURL := fmt.Sprintf(executeURLTemplate, c.env.Host, req.QueryID)
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -257,11 +358,21 @@ func (c *duneClient) QueryExecute(req models.ExecuteRequest) (*models.ExecuteRes
 	if err := executeResp.HasError(); err != nil {
 		return nil, err
 	}
+	c.trackExecution(models.ExecutionUsage{
+		ExecutionID:    executeResp.ExecutionID,
+		QueryID:        req.QueryID,
+		ParametersHash: contentHash(req.QueryParameters),
+		Performance:    req.Performance,
+		State:          executeResp.State,
+	})
 
 	return &executeResp, nil
 }
 
 func (c *duneClient) SQLExecute(req models.ExecuteSQLRequest) (*models.ExecuteResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
 	executeURL := fmt.Sprintf(sqlExecuteURLTemplate, c.env.Host)
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -283,11 +394,21 @@ func (c *duneClient) SQLExecute(req models.ExecuteSQLRequest) (*models.ExecuteRe
 	if err := executeResp.HasError(); err != nil {
 		return nil, err
 	}
+	c.trackExecution(models.ExecutionUsage{
+		ExecutionID:    executeResp.ExecutionID,
+		ParametersHash: contentHash(req.QueryParameters),
+		SQLHash:        contentHash(req.SQL),
+		Performance:    req.Performance,
+		State:          executeResp.State,
+	})
 
 	return &executeResp, nil
 }
 
 func (c *duneClient) QueryPipelineExecute(req models.PipelineExecuteRequest) (*models.PipelineExecuteResponse, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
 	executeURL := fmt.Sprintf(pipelineExecuteURLTemplate, c.env.Host, req.QueryID)
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -306,6 +427,7 @@ func (c *duneClient) QueryPipelineExecute(req models.PipelineExecuteRequest) (*m
 
 	var pipelineResp models.PipelineExecuteResponse
 	decodeBody(resp, &pipelineResp)
+	c.trackPipeline(pipelineResp.PipelineExecutionID, req.Performance)
 
 	return &pipelineResp, nil
 }
@@ -323,6 +445,7 @@ func (c *duneClient) PipelineStatus(pipelineExecutionID string) (*models.Pipelin
 
 	var pipelineStatusResp models.PipelineStatusResponse
 	decodeBody(resp, &pipelineStatusResp)
+	c.trackPipelineNodes(pipelineExecutionID, &pipelineStatusResp)
 
 	return &pipelineStatusResp, nil
 }
@@ -343,6 +466,8 @@ func (c *duneClient) QueryStatus(executionID string) (*models.StatusResponse, er
 	if err := statusResp.HasError(); err != nil {
 		return nil, err
 	}
+	c.updateExecution(statusResp.ExecutionID, statusResp.State, statusResp.ExecutionStartedAt,
+		statusResp.ExecutionEndedAt, statusResp.ResultMetadata, statusResp.ExecutionCostCredits)
 
 	return &statusResp, nil
 }
@@ -406,7 +531,13 @@ func (c *duneClient) getResultsCSV(url string) (io.Reader, error) {
 
 func (c *duneClient) QueryResultsV2(executionID string, options models.ResultOptions) (*models.ResultsResponse, error) {
 	url := fmt.Sprintf(executionResultsURLTemplate, c.env.Host, executionID)
-	return c.getResults(url, options)
+	results, err := c.getResults(url, options)
+	if err != nil {
+		return nil, err
+	}
+	c.updateExecution(executionID, results.State, results.ExecutionStartedAt, results.ExecutionEndedAt,
+		&results.Result.Metadata, results.ExecutionCostCredits)
+	return results, nil
 }
 
 func (c *duneClient) ResultsByQueryID(queryID string, options models.ResultOptions) (*models.ResultsResponse, error) {
@@ -468,11 +599,16 @@ func (c *duneClient) getUsage(startDate, endDate *string) (*models.UsageResponse
 }
 
 func (c *duneClient) ListUploads(limit, offset int) (*models.UploadsListResponse, error) {
+	return c.listUploads(context.Background(), limit, offset)
+}
+
+// listUploads is ListUploads with a context that cancels the request
+func (c *duneClient) listUploads(ctx context.Context, limit, offset int) (*models.UploadsListResponse, error) {
 	listURL := fmt.Sprintf(listUploadsURLTemplate, c.env.Host)
 
 	params := fmt.Sprintf("?limit=%d&offset=%d", limit, offset)
 
-	req, err := http.NewRequest("GET", listURL+params, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL+params, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -489,6 +625,13 @@ func (c *duneClient) ListUploads(limit, offset int) (*models.UploadsListResponse
 }
 
 func (c *duneClient) CreateUpload(req models.UploadsCreateRequest) (*models.UploadsCreateResponse, error) {
+	return c.createUpload(context.Background(), req)
+}
+
+// createUpload is CreateUpload with a context that cancels the request
+func (c *duneClient) createUpload(
+	ctx context.Context, req models.UploadsCreateRequest,
+) (*models.UploadsCreateResponse, error) {
 	createURL := fmt.Sprintf(createTableURLTemplate, c.env.Host)
 
 	jsonData, err := json.Marshal(req)
@@ -496,7 +639,7 @@ func (c *duneClient) CreateUpload(req models.UploadsCreateRequest) (*models.Uplo
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("POST", createURL, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", createURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -537,9 +680,16 @@ func (c *duneClient) UploadCSV(req models.UploadsCSVRequest) (*models.UploadsCSV
 }
 
 func (c *duneClient) DeleteUpload(namespace, tableName string) (*models.UploadsDeleteResponse, error) {
+	return c.deleteUpload(context.Background(), namespace, tableName)
+}
+
+// deleteUpload is DeleteUpload with a context that cancels the request
+func (c *duneClient) deleteUpload(
+	ctx context.Context, namespace, tableName string,
+) (*models.UploadsDeleteResponse, error) {
 	deleteURL := fmt.Sprintf(deleteTableURLTemplate, c.env.Host, url.PathEscape(namespace), url.PathEscape(tableName))
 
-	req, err := http.NewRequest("DELETE", deleteURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", deleteURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -556,9 +706,16 @@ func (c *duneClient) DeleteUpload(namespace, tableName string) (*models.UploadsD
 }
 
 func (c *duneClient) ClearUpload(namespace, tableName string) (*models.UploadsClearResponse, error) {
+	return c.clearUpload(context.Background(), namespace, tableName)
+}
+
+// clearUpload is ClearUpload with a context that cancels the request
+func (c *duneClient) clearUpload(
+	ctx context.Context, namespace, tableName string,
+) (*models.UploadsClearResponse, error) {
 	clearURL := fmt.Sprintf(clearTableURLTemplate, c.env.Host, url.PathEscape(namespace), url.PathEscape(tableName))
 
-	req, err := http.NewRequest("POST", clearURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", clearURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -576,10 +733,17 @@ func (c *duneClient) ClearUpload(namespace, tableName string) (*models.UploadsCl
 
 func (c *duneClient) InsertIntoUpload(
 	namespace, tableName, data, contentType string,
+) (*models.UploadsInsertResponse, error) {
+	return c.insertIntoUpload(context.Background(), namespace, tableName, data, contentType)
+}
+
+// insertIntoUpload is InsertIntoUpload with a context that cancels the request
+func (c *duneClient) insertIntoUpload(
+	ctx context.Context, namespace, tableName, data, contentType string,
 ) (*models.UploadsInsertResponse, error) {
 	insertURL := fmt.Sprintf(insertTableURLTemplate, c.env.Host, url.PathEscape(namespace), url.PathEscape(tableName))
 
-	req, err := http.NewRequest("POST", insertURL, bytes.NewBufferString(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", insertURL, bytes.NewBufferString(data))
 	if err != nil {
 		return nil, err
 	}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 )
 
 var ErrorReqUnsuccessful = errors.New("request was not successful")
@@ -13,6 +15,27 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// HTTPError is returned for API responses with a non 2xx status, it wraps ErrorReqUnsuccessful.
+// Message is the error reported by the API, or the raw body when it is not a JSON error.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s [%d]: %s", ErrorReqUnsuccessful, e.StatusCode, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return ErrorReqUnsuccessful
+}
+
+// isStatus reports whether err is an API response with the given status code
+func isStatus(err error, statusCode int) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == statusCode
+}
+
 func decodeBody(resp *http.Response, dest interface{}) error {
 	defer resp.Body.Close()
 	err := json.NewDecoder(resp.Body).Decode(dest)
@@ -31,12 +54,15 @@ func httpRequest(apiKey string, req *http.Request) (*http.Response, error) {
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		defer resp.Body.Close()
-		var errorResponse ErrorResponse
-		err := json.NewDecoder(resp.Body).Decode(&errorResponse)
+		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read error response body: %w", err)
 		}
-		return resp, fmt.Errorf("%w [%d]: %s", ErrorReqUnsuccessful, resp.StatusCode, errorResponse.Error)
+		var errorResponse ErrorResponse
+		if err := json.Unmarshal(body, &errorResponse); err != nil {
+			errorResponse.Error = strings.TrimSpace(string(body))
+		}
+		return resp, &HTTPError{StatusCode: resp.StatusCode, Message: errorResponse.Error}
 	}
 
 	return resp, nil
@@ -0,0 +1,164 @@
+package dune
+
+import (
+	"context"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// fetchPage requests the page of limit items starting at offset. It returns the offset of the
+// next page, or nil if it was the last page.
+type fetchPage[T any] func(offset, limit int) ([]T, *int, error)
+
+// Iterator walks the items of a paginated endpoint, requesting pages as they are needed:
+//
+//	it := client.AllUploads(ctx, models.PaginationOptions{})
+//	for it.Next() {
+//		table := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+type Iterator[T any] struct {
+	ctx      context.Context
+	fetch    fetchPage[T]
+	pageSize int
+	maxItems int
+
+	next    *int
+	page    []T
+	current T
+	count   int
+	err     error
+}
+
+func newIterator[T any](
+	ctx context.Context, options models.PaginationOptions, defaultPageSize int, fetch fetchPage[T],
+) *Iterator[T] {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	start := 0
+	return &Iterator[T]{
+		ctx:      ctx,
+		fetch:    fetch,
+		pageSize: pageSize,
+		maxItems: options.MaxItems,
+		next:     &start,
+	}
+}
+
+// Next advances to the next item, fetching the next page when the current one is exhausted.
+// It returns false at the end of the items, when MaxItems is reached, or on error.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil || (it.maxItems > 0 && it.count >= it.maxItems) {
+		return false
+	}
+	for len(it.page) == 0 {
+		if it.next == nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		limit := it.pageSize
+		if it.maxItems > 0 {
+			limit = min(limit, it.maxItems-it.count)
+		}
+		items, next, err := it.fetch(*it.next, limit)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.next = items, next
+		if len(items) == 0 {
+			it.next = nil
+		}
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	it.count++
+	return true
+}
+
+// Value returns the current item
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Collect returns all the remaining items
+func (it *Iterator[T]) Collect() ([]T, error) {
+	var items []T
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+// AllUploads iterates over the uploaded tables
+func (c *duneClient) AllUploads(
+	ctx context.Context, options models.PaginationOptions,
+) *Iterator[models.UploadsListElement] {
+	return newIterator(ctx, options, 100, func(offset, limit int) ([]models.UploadsListElement, *int, error) {
+		resp, err := c.listUploads(ctx, limit, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.NextOffset != nil {
+			return resp.Tables, resp.NextOffset, nil
+		}
+		// without a next offset, as in AllDatasets, only a full page may be followed by another
+		if len(resp.Tables) < limit {
+			return resp.Tables, nil, nil
+		}
+		next := offset + len(resp.Tables)
+		return resp.Tables, &next, nil
+	})
+}
+
+// AllDatasets iterates over the datasets, optionally filtered by owner and type like ListDatasets
+func (c *duneClient) AllDatasets(
+	ctx context.Context, ownerHandle, datasetType string, options models.PaginationOptions,
+) *Iterator[models.DatasetResponse] {
+	return newIterator(ctx, options, 100, func(offset, limit int) ([]models.DatasetResponse, *int, error) {
+		resp, err := c.ListDatasets(limit, offset, ownerHandle, datasetType)
+		if err != nil {
+			return nil, nil, err
+		}
+		next := offset + len(resp.Datasets)
+		if next >= resp.Total && (resp.Total > 0 || len(resp.Datasets) < limit) {
+			return resp.Datasets, nil, nil
+		}
+		return resp.Datasets, &next, nil
+	})
+}
+
+// SearchAll iterates over all the results of a dataset search. The Limit and Offset of req
+// are ignored, pages follow the pagination returned by the API.
+func (c *duneClient) SearchAll(
+	ctx context.Context, req models.SearchDatasetsRequest, options models.PaginationOptions,
+) *Iterator[models.SearchDatasetResult] {
+	return newIterator(ctx, options, 50, func(offset, limit int) ([]models.SearchDatasetResult, *int, error) {
+		pageReq := req
+		pageLimit, pageOffset := int32(limit), int32(offset)
+		pageReq.Limit, pageReq.Offset = &pageLimit, &pageOffset
+		resp, err := c.SearchDatasets(pageReq)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !resp.Pagination.HasMore {
+			return resp.Results, nil, nil
+		}
+		next := offset + len(resp.Results)
+		if resp.Pagination.NextOffset != nil {
+			next = int(*resp.Pagination.NextOffset)
+		}
+		return resp.Results, &next, nil
+	})
+}
@@ -0,0 +1,142 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllUploads(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		// pages are capped at 2 tables whatever the limit
+		resp := models.UploadsListResponse{}
+		for i := offset; i < offset+min(limit, 2) && i < 5; i++ {
+			resp.Tables = append(resp.Tables, models.UploadsListElement{FullName: fmt.Sprintf("dune.ns.t%d", i)})
+		}
+		if next := offset + len(resp.Tables); next < 5 {
+			resp.NextOffset = &next
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	tables, err := client.AllUploads(context.Background(), models.PaginationOptions{PageSize: 3}).Collect()
+
+	require.NoError(t, err)
+	require.Len(t, tables, 5)
+	require.Equal(t, "dune.ns.t4", tables[4].FullName)
+	require.Equal(t, []string{"limit=3&offset=0", "limit=3&offset=2", "limit=3&offset=4"}, requests)
+
+	requests = nil
+	tables, err = client.AllUploads(context.Background(), models.PaginationOptions{PageSize: 2, MaxItems: 3}).Collect()
+
+	require.NoError(t, err)
+	require.Len(t, tables, 3)
+	require.Equal(t, []string{"limit=2&offset=0", "limit=1&offset=2"}, requests)
+}
+
+func TestAllUploadsWithoutNextOffset(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		resp := models.UploadsListResponse{}
+		for i := offset; i < offset+limit && i < 5; i++ {
+			resp.Tables = append(resp.Tables, models.UploadsListElement{FullName: fmt.Sprintf("dune.ns.t%d", i)})
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	tables, err := client.AllUploads(context.Background(), models.PaginationOptions{PageSize: 2}).Collect()
+
+	require.NoError(t, err)
+	require.Len(t, tables, 5)
+	// the short third page ends the listing
+	require.Equal(t, []string{"limit=2&offset=0", "limit=2&offset=2", "limit=2&offset=4"}, requests)
+}
+
+func TestAllDatasets(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		datasets := []models.DatasetResponse{}
+		for i := offset; i < offset+2 && i < 3; i++ {
+			datasets = append(datasets, models.DatasetResponse{FullName: fmt.Sprintf("spell_%d", i)})
+		}
+		json.NewEncoder(w).Encode(models.ListDatasetsResponse{Datasets: datasets, Total: 3})
+	})
+
+	it := client.AllDatasets(context.Background(), "dune", "spell", models.PaginationOptions{PageSize: 2})
+	var names []string
+	for it.Next() {
+		names = append(names, it.Value().FullName)
+	}
+
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"spell_0", "spell_1", "spell_2"}, names)
+	require.Equal(t, []string{
+		"limit=2&offset=0&owner_handle=dune&type=spell",
+		"limit=2&offset=2&owner_handle=dune&type=spell",
+	}, requests)
+}
+
+func TestSearchAll(t *testing.T) {
+	var offsets []int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req models.SearchDatasetsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		offsets = append(offsets, *req.Offset)
+
+		resp := models.SearchDatasetsResponse{
+			Total:   3,
+			Results: []models.SearchDatasetResult{{FullName: fmt.Sprintf("result_%d", *req.Offset)}},
+		}
+		if *req.Offset == 0 {
+			// the API may skip ahead
+			next := int32(10)
+			resp.Pagination = models.SearchDatasetsPagination{HasMore: true, NextOffset: &next}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	query := "uniswap"
+	results, err := client.SearchAll(context.Background(), models.SearchDatasetsRequest{Query: &query},
+		models.PaginationOptions{}).Collect()
+
+	require.NoError(t, err)
+	require.Equal(t, []int32{0, 10}, offsets)
+	require.Equal(t, "result_10", results[1].FullName)
+}
+
+func TestIteratorStopsOnCancelAndError(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "boom"})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it := client.AllUploads(ctx, models.PaginationOptions{})
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Err(), context.Canceled)
+	require.Equal(t, 0, calls)
+
+	it = client.AllUploads(context.Background(), models.PaginationOptions{})
+	require.False(t, it.Next())
+	require.ErrorContains(t, it.Err(), "boom")
+	require.False(t, it.Next())
+	require.Equal(t, 1, calls)
+}
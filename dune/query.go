@@ -3,6 +3,7 @@ package dune
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -82,10 +83,72 @@ func (c *duneClient) UpdateQuery(queryID int, req models.UpdateQueryRequest) (*m
 	return &updateResp, nil
 }
 
+var ErrorQueryVersionConflict = errors.New("query version has changed")
+
+// QueryVersionConflictError is returned by UpdateQueryIfVersion when the saved query
+// is no longer at the expected version. It matches ErrorQueryVersionConflict with errors.Is.
+type QueryVersionConflictError struct {
+	QueryID         int
+	ExpectedVersion int
+	// Current is the state of the saved query that was read before refusing the update, it is nil
+	// when the conflict was reported without the current query
+	Current *models.GetQueryResponse
+}
+
+func (e *QueryVersionConflictError) Error() string {
+	if e.Current == nil {
+		return fmt.Sprintf("%s: query %d is not at version %d", ErrorQueryVersionConflict, e.QueryID, e.ExpectedVersion)
+	}
+	return fmt.Sprintf("%s: query %d is at version %d, expected %d",
+		ErrorQueryVersionConflict, e.QueryID, e.Current.Version, e.ExpectedVersion)
+}
+
+func (e *QueryVersionConflictError) Is(target error) bool {
+	return target == ErrorQueryVersionConflict
+}
+
+// UpdateQueryIfVersion re-reads the query and only applies the update if it is still at
+// expectedVersion. The check happens client side, so it narrows the window for lost
+// updates but cannot rule out a concurrent write between the read and the update.
+func (c *duneClient) UpdateQueryIfVersion(
+	queryID, expectedVersion int, req models.UpdateQueryRequest,
+) (*models.UpdateQueryResponse, error) {
+	current, err := c.GetQuery(queryID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != expectedVersion {
+		return nil, &QueryVersionConflictError{
+			QueryID:         queryID,
+			ExpectedVersion: expectedVersion,
+			Current:         current,
+		}
+	}
+
+	return c.UpdateQuery(queryID, req)
+}
+
 func (c *duneClient) ArchiveQuery(queryID int) (*models.UpdateQueryResponse, error) {
-	archiveURL := fmt.Sprintf(archiveQueryURLTemplate, c.env.Host, queryID)
+	return c.postQueryAction(archiveQueryURLTemplate, queryID)
+}
+
+func (c *duneClient) UnarchiveQuery(queryID int) (*models.UpdateQueryResponse, error) {
+	return c.postQueryAction(unarchiveQueryURLTemplate, queryID)
+}
+
+func (c *duneClient) MakeQueryPrivate(queryID int) (*models.UpdateQueryResponse, error) {
+	return c.postQueryAction(privateQueryURLTemplate, queryID)
+}
 
-	req, err := http.NewRequest("POST", archiveURL, nil)
+func (c *duneClient) MakeQueryPublic(queryID int) (*models.UpdateQueryResponse, error) {
+	return c.postQueryAction(unprivateQueryURLTemplate, queryID)
+}
+
+// postQueryAction sends a bodyless POST to one of the /query/{id}/<action> endpoints
+func (c *duneClient) postQueryAction(urlTemplate string, queryID int) (*models.UpdateQueryResponse, error) {
+	actionURL := fmt.Sprintf(urlTemplate, c.env.Host, queryID)
+
+	req, err := http.NewRequest("POST", actionURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -95,10 +158,34 @@ func (c *duneClient) ArchiveQuery(queryID int) (*models.UpdateQueryResponse, err
 		return nil, err
 	}
 
-	var archiveResp models.UpdateQueryResponse
-	if err := decodeBody(resp, &archiveResp); err != nil {
+	var actionResp models.UpdateQueryResponse
+	if err := decodeBody(resp, &actionResp); err != nil {
+		return nil, err
+	}
+
+	return &actionResp, nil
+}
+
+func (c *duneClient) ListQueries(req models.ListQueriesRequest) (*models.ListQueriesResponse, error) {
+	listURL := fmt.Sprintf(listQueriesURLTemplate, c.env.Host)
+	if params := req.ToURLValues().Encode(); params != "" {
+		listURL += "?" + params
+	}
+
+	httpReq, err := http.NewRequest("GET", listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpRequest(c.env.APIKey, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var listResp models.ListQueriesResponse
+	if err := decodeBody(resp, &listResp); err != nil {
 		return nil, err
 	}
 
-	return &archiveResp, nil
+	return &listResp, nil
 }
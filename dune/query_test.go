@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/duneanalytics/duneapi-client-go/config"
@@ -198,6 +199,108 @@ func TestArchiveQuery(t *testing.T) {
 	require.Equal(t, 42, resp.QueryID)
 }
 
+func TestUpdateQueryIfVersion(t *testing.T) {
+	var patched bool
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(models.GetQueryResponse{QueryID: 42, Name: "Remote", Version: 3})
+		case "PATCH":
+			patched = true
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(models.UpdateQueryResponse{QueryID: 42})
+		}
+	})
+
+	newName := "Updated Name"
+	_, err := client.UpdateQueryIfVersion(42, 2, models.UpdateQueryRequest{Name: &newName})
+
+	require.ErrorIs(t, err, ErrorQueryVersionConflict)
+	var conflict *QueryVersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, 2, conflict.ExpectedVersion)
+	require.Equal(t, 3, conflict.Current.Version)
+	require.Equal(t, "Remote", conflict.Current.Name)
+	require.EqualError(t, err, "query version has changed: query 42 is at version 3, expected 2")
+	require.False(t, patched)
+
+	conflict.Current = nil
+	require.EqualError(t, conflict, "query version has changed: query 42 is not at version 2")
+
+	resp, err := client.UpdateQueryIfVersion(42, 3, models.UpdateQueryRequest{Name: &newName})
+
+	require.NoError(t, err)
+	require.True(t, patched)
+	require.Equal(t, 42, resp.QueryID)
+}
+
+func TestQueryActions(t *testing.T) {
+	var gotMethod, gotPath string
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.Path
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(models.UpdateQueryResponse{QueryID: 42})
+	})
+
+	for path, action := range map[string]func(int) (*models.UpdateQueryResponse, error){
+		"/api/v1/query/42/unarchive": client.UnarchiveQuery,
+		"/api/v1/query/42/private":   client.MakeQueryPrivate,
+		"/api/v1/query/42/unprivate": client.MakeQueryPublic,
+	} {
+		resp, err := action(42)
+
+		require.NoError(t, err)
+		require.Equal(t, "POST", gotMethod)
+		require.Equal(t, path, gotPath)
+		require.Equal(t, 42, resp.QueryID)
+	}
+}
+
+func TestListQueries(t *testing.T) {
+	var gotPath string
+	var gotQuery url.Values
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query()
+
+		nextOffset := 20
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(models.ListQueriesResponse{
+			Queries: []models.QueryListElement{
+				{QueryID: 1, Name: "First", Owner: "alice", Tags: []string{"dex"}},
+			},
+			Total:      21,
+			NextOffset: &nextOffset,
+		})
+	})
+
+	archived := false
+	resp, err := client.ListQueries(models.ListQueriesRequest{
+		Limit:    20,
+		Owner:    "alice",
+		Tags:     []string{"dex", "prices"},
+		Archived: &archived,
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/queries", gotPath)
+	require.Equal(t, "20", gotQuery.Get("limit"))
+	require.Equal(t, "", gotQuery.Get("offset"))
+	require.Equal(t, "alice", gotQuery.Get("owner"))
+	require.Equal(t, "dex,prices", gotQuery.Get("tags"))
+	require.Equal(t, "false", gotQuery.Get("archived"))
+	require.Len(t, resp.Queries, 1)
+	require.Equal(t, "First", resp.Queries[0].Name)
+	require.Equal(t, 21, resp.Total)
+	require.Equal(t, 20, *resp.NextOffset)
+}
+
 func TestCreateQueryError(t *testing.T) {
 	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
@@ -0,0 +1,378 @@
+package dune
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// number of unchanged lines shown around each change in the SQL diff
+const diffContextLines = 3
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffHunk is a group of SQL line changes with their surrounding context, as in a unified diff.
+// Line numbers are 1-based, OldStart refers to the saved query and NewStart to the local version.
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// FieldChange is a scalar field whose value differs, Old is the saved value and New the local one
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// ParameterChange describes a parameter that was added (Old is nil), removed (New is nil) or changed
+type ParameterChange struct {
+	Key string                 `json:"key"`
+	Old *models.QueryParameter `json:"old,omitempty"`
+	New *models.QueryParameter `json:"new,omitempty"`
+}
+
+// QueryDiff describes the changes needed to turn a saved query into a local version of it
+type QueryDiff struct {
+	QueryID     int               `json:"query_id"`
+	Fields      []FieldChange     `json:"fields,omitempty"`
+	SQL         []DiffHunk        `json:"sql,omitempty"`
+	TagsAdded   []string          `json:"tags_added,omitempty"`
+	TagsRemoved []string          `json:"tags_removed,omitempty"`
+	Parameters  []ParameterChange `json:"parameters,omitempty"`
+}
+
+// DiffCreateQuery compares the request that would create a query to the current state of a saved query
+func DiffCreateQuery(local models.CreateQueryRequest, remote *models.GetQueryResponse) *QueryDiff {
+	desired := *remote
+	desired.Name = local.Name
+	desired.QuerySQL = local.QuerySQL
+	desired.Description = local.Description
+	desired.IsPrivate = local.IsPrivate
+	desired.Tags = local.Tags
+	desired.Parameters = local.Parameters
+	return diffQuery(remote, &desired)
+}
+
+// DiffUpdateQuery compares an update request to the current state of a saved query.
+// Fields left unset in the request are considered unchanged, as they are by UpdateQuery.
+func DiffUpdateQuery(local models.UpdateQueryRequest, remote *models.GetQueryResponse) *QueryDiff {
+	desired := *remote
+	if local.Name != nil {
+		desired.Name = *local.Name
+	}
+	if local.QuerySQL != nil {
+		desired.QuerySQL = *local.QuerySQL
+	}
+	if local.Description != nil {
+		desired.Description = *local.Description
+	}
+	if local.IsPrivate != nil {
+		desired.IsPrivate = *local.IsPrivate
+	}
+	if local.IsArchived != nil {
+		desired.IsArchived = *local.IsArchived
+	}
+	if local.Tags != nil {
+		desired.Tags = local.Tags
+	}
+	if local.Parameters != nil {
+		desired.Parameters = local.Parameters
+	}
+	return diffQuery(remote, &desired)
+}
+
+func diffQuery(remote, desired *models.GetQueryResponse) *QueryDiff {
+	d := &QueryDiff{QueryID: remote.QueryID}
+
+	if remote.Name != desired.Name {
+		d.Fields = append(d.Fields, FieldChange{Field: "name", Old: remote.Name, New: desired.Name})
+	}
+	if remote.Description != desired.Description {
+		d.Fields = append(d.Fields, FieldChange{Field: "description", Old: remote.Description, New: desired.Description})
+	}
+	if remote.IsPrivate != desired.IsPrivate {
+		d.Fields = append(d.Fields, FieldChange{Field: "is_private", Old: remote.IsPrivate, New: desired.IsPrivate})
+	}
+	if remote.IsArchived != desired.IsArchived {
+		d.Fields = append(d.Fields, FieldChange{Field: "is_archived", Old: remote.IsArchived, New: desired.IsArchived})
+	}
+
+	d.SQL = diffSQL(remote.QuerySQL, desired.QuerySQL)
+
+	for _, tag := range desired.Tags {
+		if !slices.Contains(remote.Tags, tag) {
+			d.TagsAdded = append(d.TagsAdded, tag)
+		}
+	}
+	for _, tag := range remote.Tags {
+		if !slices.Contains(desired.Tags, tag) {
+			d.TagsRemoved = append(d.TagsRemoved, tag)
+		}
+	}
+
+	d.Parameters = diffParameters(remote.Parameters, desired.Parameters)
+	return d
+}
+
+func diffParameters(remote, desired []models.QueryParameter) []ParameterChange {
+	var changes []ParameterChange
+	for i := range desired {
+		idx := slices.IndexFunc(remote, func(p models.QueryParameter) bool { return p.Key == desired[i].Key })
+		if idx < 0 {
+			changes = append(changes, ParameterChange{Key: desired[i].Key, New: &desired[i]})
+		} else if !sameParameter(remote[idx], desired[i]) {
+			changes = append(changes, ParameterChange{Key: desired[i].Key, Old: &remote[idx], New: &desired[i]})
+		}
+	}
+	for i := range remote {
+		if !slices.ContainsFunc(desired, func(p models.QueryParameter) bool { return p.Key == remote[i].Key }) {
+			changes = append(changes, ParameterChange{Key: remote[i].Key, Old: &remote[i]})
+		}
+	}
+	return changes
+}
+
+func sameParameter(a, b models.QueryParameter) bool {
+	return a.Key == b.Key && a.Type == b.Type && a.Value == b.Value && slices.Equal(a.EnumOptions, b.EnumOptions)
+}
+
+// HasChanges reports whether applying the local version would change the saved query
+func (d *QueryDiff) HasChanges() bool {
+	return len(d.Fields) > 0 || len(d.SQL) > 0 || len(d.TagsAdded) > 0 ||
+		len(d.TagsRemoved) > 0 || len(d.Parameters) > 0
+}
+
+// ChangedFields returns the API names of the fields that differ
+func (d *QueryDiff) ChangedFields() []string {
+	var fields []string
+	for _, f := range d.Fields {
+		fields = append(fields, f.Field)
+	}
+	if len(d.SQL) > 0 {
+		fields = append(fields, "query_sql")
+	}
+	if len(d.TagsAdded) > 0 || len(d.TagsRemoved) > 0 {
+		fields = append(fields, "tags")
+	}
+	if len(d.Parameters) > 0 {
+		fields = append(fields, "parameters")
+	}
+	return fields
+}
+
+// JSON renders the diff as indented JSON
+func (d *QueryDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Text renders the diff in a human-readable form, with the SQL as a unified diff
+func (d *QueryDiff) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "query %d\n", d.QueryID)
+	if !d.HasChanges() {
+		b.WriteString("  no changes\n")
+		return b.String()
+	}
+
+	for _, f := range d.Fields {
+		fmt.Fprintf(&b, "~ %s: %#v -> %#v\n", f.Field, f.Old, f.New)
+	}
+	for _, tag := range d.TagsAdded {
+		fmt.Fprintf(&b, "+ tag %s\n", tag)
+	}
+	for _, tag := range d.TagsRemoved {
+		fmt.Fprintf(&b, "- tag %s\n", tag)
+	}
+	for _, p := range d.Parameters {
+		switch {
+		case p.Old == nil:
+			fmt.Fprintf(&b, "+ parameter %s (%s) = %q\n", p.Key, p.New.Type, p.New.Value)
+		case p.New == nil:
+			fmt.Fprintf(&b, "- parameter %s (%s) = %q\n", p.Key, p.Old.Type, p.Old.Value)
+		default:
+			fmt.Fprintf(&b, "~ parameter %s: (%s) %q -> (%s) %q\n", p.Key, p.Old.Type, p.Old.Value, p.New.Type, p.New.Value)
+		}
+	}
+
+	if len(d.SQL) > 0 {
+		fmt.Fprintf(&b, "--- query %d\n+++ local\n", d.QueryID)
+		for _, h := range d.SQL {
+			fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+			for _, l := range h.Lines {
+				switch l.Op {
+				case DiffInsert:
+					b.WriteString("+")
+				case DiffDelete:
+					b.WriteString("-")
+				default:
+					b.WriteString(" ")
+				}
+				b.WriteString(l.Text)
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// diffSQL computes the line diff between two SQL texts and groups it into hunks.
+// Trailing whitespace at the end of the texts is ignored, editors usually add a final newline.
+func diffSQL(oldSQL, newSQL string) []DiffHunk {
+	oldLines := splitLines(oldSQL)
+	newLines := splitLines(newSQL)
+	if slices.Equal(oldLines, newLines) {
+		return nil
+	}
+	return buildHunks(diffLines(oldLines, newLines))
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), " \t\r\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns the edit script from a to b based on their longest common subsequence.
+// The common prefix and suffix are matched first, so the quadratic LCS table only covers the
+// changed region: edits to a long query usually touch a few lines.
+func diffLines(a, b []string) []DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+	lines = append(lines, diffLCS(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return lines
+}
+
+// diffLCS returns the edit script from a to b from the table of their longest common subsequences
+func diffLCS(a, b []string) []DiffLine {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return lines
+}
+
+func buildHunks(lines []DiffLine) []DiffHunk {
+	var hunks []DiffHunk
+	// oldPos and newPos hold the 1-based line numbers of lines[i]
+	oldPos, newPos := make([]int, len(lines)), make([]int, len(lines))
+	o, n := 1, 1
+	for i, l := range lines {
+		oldPos[i], newPos[i] = o, n
+		if l.Op != DiffInsert {
+			o++
+		}
+		if l.Op != DiffDelete {
+			n++
+		}
+	}
+
+	i := 0
+	for i < len(lines) {
+		if lines[i].Op == DiffEqual {
+			i++
+			continue
+		}
+		start := max(i-diffContextLines, 0)
+		// extend the hunk until there are more than 2*context equal lines in a row
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Op == DiffEqual {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContextLines {
+				end = min(end+diffContextLines, len(lines))
+				break
+			}
+			end = run
+		}
+
+		h := DiffHunk{
+			OldStart: oldPos[start],
+			NewStart: newPos[start],
+			Lines:    lines[start:end],
+		}
+		for _, l := range h.Lines {
+			if l.Op != DiffInsert {
+				h.OldLines++
+			}
+			if l.Op != DiffDelete {
+				h.NewLines++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
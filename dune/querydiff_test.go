@@ -0,0 +1,132 @@
+package dune
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCreateQuery(t *testing.T) {
+	remote := &models.GetQueryResponse{
+		QueryID:   42,
+		Name:      "Top traders",
+		QuerySQL:  "SELECT trader\nFROM dex.trades\nWHERE block_time > now() - interval '7' day\nLIMIT 10",
+		IsPrivate: false,
+		Tags:      []string{"dex", "old"},
+		Parameters: []models.QueryParameter{
+			{Key: "days", Type: "number", Value: "7"},
+			{Key: "chain", Type: "text", Value: "ethereum"},
+		},
+	}
+	local := models.CreateQueryRequest{
+		Name:      "Top traders",
+		QuerySQL:  "SELECT trader, sum(amount_usd)\nFROM dex.trades\nWHERE block_time > now() - interval '7' day\nLIMIT 10\n",
+		IsPrivate: true,
+		Tags:      []string{"dex", "new"},
+		Parameters: []models.QueryParameter{
+			{Key: "days", Type: "number", Value: "30"},
+			{Key: "limit", Type: "number", Value: "10"},
+		},
+	}
+
+	diff := DiffCreateQuery(local, remote)
+
+	require.True(t, diff.HasChanges())
+	require.Equal(t, []string{"is_private", "query_sql", "tags", "parameters"}, diff.ChangedFields())
+	require.Equal(t, []FieldChange{{Field: "is_private", Old: false, New: true}}, diff.Fields)
+	require.Equal(t, []string{"new"}, diff.TagsAdded)
+	require.Equal(t, []string{"old"}, diff.TagsRemoved)
+
+	require.Len(t, diff.Parameters, 3)
+	require.Equal(t, "days", diff.Parameters[0].Key)
+	require.Equal(t, "7", diff.Parameters[0].Old.Value)
+	require.Equal(t, "30", diff.Parameters[0].New.Value)
+	require.Equal(t, "limit", diff.Parameters[1].Key)
+	require.Nil(t, diff.Parameters[1].Old)
+	require.Equal(t, "chain", diff.Parameters[2].Key)
+	require.Nil(t, diff.Parameters[2].New)
+
+	// the trailing newline of the local file is not a change
+	require.Len(t, diff.SQL, 1)
+	require.Equal(t, DiffHunk{
+		OldStart: 1,
+		OldLines: 4,
+		NewStart: 1,
+		NewLines: 4,
+		Lines: []DiffLine{
+			{Op: DiffDelete, Text: "SELECT trader"},
+			{Op: DiffInsert, Text: "SELECT trader, sum(amount_usd)"},
+			{Op: DiffEqual, Text: "FROM dex.trades"},
+			{Op: DiffEqual, Text: "WHERE block_time > now() - interval '7' day"},
+			{Op: DiffEqual, Text: "LIMIT 10"},
+		},
+	}, diff.SQL[0])
+
+	text := diff.Text()
+	require.Contains(t, text, "~ is_private: false -> true")
+	require.Contains(t, text, "@@ -1,4 +1,4 @@\n-SELECT trader\n+SELECT trader, sum(amount_usd)\n FROM dex.trades\n")
+	require.Contains(t, text, "~ parameter days: (number) \"7\" -> (number) \"30\"")
+
+	out, err := diff.JSON()
+	require.NoError(t, err)
+	var decoded QueryDiff
+	require.NoError(t, json.Unmarshal(out, &decoded))
+	require.Equal(t, diff.SQL, decoded.SQL)
+	require.Equal(t, diff.TagsAdded, decoded.TagsAdded)
+}
+
+func TestDiffUpdateQueryIgnoresUnsetFields(t *testing.T) {
+	remote := &models.GetQueryResponse{
+		QueryID:  42,
+		Name:     "Name",
+		QuerySQL: "SELECT 1",
+		Tags:     []string{"a"},
+	}
+
+	require.False(t, DiffUpdateQuery(models.UpdateQueryRequest{}, remote).HasChanges())
+
+	name := "Renamed"
+	diff := DiffUpdateQuery(models.UpdateQueryRequest{Name: &name}, remote)
+	require.Equal(t, []string{"name"}, diff.ChangedFields())
+	require.Contains(t, diff.Text(), "~ name: \"Name\" -> \"Renamed\"")
+}
+
+func TestDiffSQLHunks(t *testing.T) {
+	oldSQL := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	newSQL := "1\nx\n3\n4\n5\n6\n7\n8\n9\n10\ny\n12"
+
+	hunks := diffSQL(oldSQL, newSQL)
+
+	require.Len(t, hunks, 2)
+	require.Equal(t, 1, hunks[0].OldStart)
+	require.Equal(t, 5, hunks[0].OldLines)
+	require.Equal(t, 8, hunks[1].OldStart)
+	require.Equal(t, 5, hunks[1].OldLines)
+	require.Equal(t, 5, hunks[1].NewLines)
+	require.Nil(t, diffSQL("SELECT 1\n", "SELECT 1"))
+}
+
+func TestDiffSQLLongQuery(t *testing.T) {
+	// a quadratic table over the whole query would need 10^10 cells
+	oldLines := make([]string, 100000)
+	for i := range oldLines {
+		oldLines[i] = fmt.Sprintf("SELECT %d UNION ALL", i)
+	}
+	newLines := slices.Clone(oldLines)
+	newLines[50000] = "SELECT -1 UNION ALL"
+
+	hunks := diffSQL(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+
+	require.Len(t, hunks, 1)
+	require.Equal(t, 49998, hunks[0].OldStart)
+	require.Equal(t, 7, hunks[0].OldLines)
+	require.Equal(t, []DiffLine{
+		{Op: DiffDelete, Text: "SELECT 50000 UNION ALL"},
+		{Op: DiffInsert, Text: "SELECT -1 UNION ALL"},
+	}, hunks[0].Lines[3:5])
+}
@@ -0,0 +1,335 @@
+package dune
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// DefaultInferSampleRows is the number of rows scanned by InferSchema when sampleRows is zero
+const DefaultInferSampleRows = 1000
+
+var (
+	integerPattern = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	decimalPattern = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+	hexPattern     = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+
+	timestampLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999 MST",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02",
+	}
+)
+
+// maxInt256 is the largest value of an int256 column
+var maxInt256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+
+// inferredColumn accumulates the type observed for a column over the sampled rows
+type inferredColumn struct {
+	name     string
+	typ      models.ColumnType
+	nullable bool
+	// negative and aboveInt256 record whether integers below zero or above the int256 range were seen,
+	// which decides between int256 and uint256 once all rows are sampled
+	negative    bool
+	aboveInt256 bool
+}
+
+// observe widens the column type to hold a value of type typ. literal is the text of numeric values.
+func (c *inferredColumn) observe(typ models.ColumnType, literal string) {
+	if typ == "" {
+		c.nullable = true
+		return
+	}
+	if typ.IsInteger() {
+		if n, ok := new(big.Int).SetString(strings.TrimSpace(literal), 10); ok {
+			c.negative = c.negative || n.Sign() < 0
+			c.aboveInt256 = c.aboveInt256 || n.Cmp(maxInt256) > 0
+		}
+	}
+	c.typ = widenType(c.typ, typ)
+}
+
+// columnType returns the type inferred for the column. Integers too large for 64 bits are uint256
+// when none is negative, int256 when all fit in its range, and varchar otherwise.
+func (c *inferredColumn) columnType() models.ColumnType {
+	if c.typ != models.ColumnTypeInt256 && c.typ != models.ColumnTypeUint256 {
+		return c.typ
+	}
+	switch {
+	case !c.negative:
+		return models.ColumnTypeUint256
+	case !c.aboveInt256:
+		return models.ColumnTypeInt256
+	default:
+		return models.ColumnTypeVarchar
+	}
+}
+
+// widenType returns the narrowest type able to hold values of both types
+func widenType(a, b models.ColumnType) models.ColumnType {
+	if a == "" || a == b {
+		return b
+	}
+	numeric := map[models.ColumnType]int{
+		models.ColumnTypeInteger: 1,
+		models.ColumnTypeInt256:  2,
+		models.ColumnTypeUint256: 2,
+		models.ColumnTypeDouble:  3,
+	}
+	if numeric[a] > 0 && numeric[b] > 0 {
+		switch {
+		case numeric[a] == 3 || numeric[b] == 3:
+			return models.ColumnTypeDouble
+		case numeric[a] == 2 && numeric[b] == 2:
+			// the values seen decide between int256 and uint256 in columnType
+			return models.ColumnTypeInt256
+		case numeric[a] == 2:
+			return a
+		default:
+			return b
+		}
+	}
+	return models.ColumnTypeVarchar
+}
+
+// InferSchema scans the first sampleRows rows of CSV or NDJSON input and proposes a table schema.
+// Columns are typed integer, double, boolean, timestamp, varbinary (0x prefixed hex) or varchar,
+// and are nullable when a value is empty or missing in any sampled row. Integers too large for
+// 64 bits are typed uint256 when none is negative, int256 when they all fit in its range, and
+// varchar otherwise. For CSV input the first row must be the header.
+func InferSchema(r io.Reader, contentType string, sampleRows int) ([]models.UploadsColumn, error) {
+	if sampleRows <= 0 {
+		sampleRows = DefaultInferSampleRows
+	}
+
+	var columns []*inferredColumn
+	var err error
+	switch contentType {
+	case models.ContentTypeCSV:
+		columns, err = inferCSV(r, sampleRows)
+	case models.ContentTypeNDJSON:
+		columns, err = inferNDJSON(r, sampleRows)
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, use %s or %s",
+			contentType, models.ContentTypeCSV, models.ContentTypeNDJSON)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make([]models.UploadsColumn, 0, len(columns))
+	for _, c := range columns {
+		typ := c.columnType()
+		if typ == "" {
+			// only nulls were sampled
+			typ = models.ColumnTypeVarchar
+			c.nullable = true
+		}
+		schema = append(schema, models.UploadsColumn{
+			Name:     c.name,
+			Type:     string(typ),
+			Nullable: c.nullable,
+		})
+	}
+	return schema, nil
+}
+
+func inferCSV(r io.Reader, sampleRows int) ([]*inferredColumn, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]*inferredColumn, len(header))
+	for i, name := range header {
+		columns[i] = &inferredColumn{name: strings.TrimSpace(name)}
+	}
+
+	for n := 0; n < sampleRows; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(columns) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: expected %d fields, got %d", line, len(columns), len(record))
+		}
+		for i, value := range record {
+			columns[i].observe(inferStringType(value, true), value)
+		}
+	}
+	return columns, nil
+}
+
+func inferNDJSON(r io.Reader, sampleRows int) ([]*inferredColumn, error) {
+	var columns []*inferredColumn
+	byName := map[string]*inferredColumn{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), models.DefaultInsertChunkBytes)
+	line, rows := 0, 0
+	for rows < sampleRows && scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		rows++
+
+		keys, row, err := decodeNDJSONRow(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		for _, name := range keys {
+			value := row[name]
+			c, ok := byName[name]
+			if !ok {
+				// a column missing from earlier rows held nulls in them
+				c = &inferredColumn{name: name, nullable: rows > 1}
+				byName[name] = c
+				columns = append(columns, c)
+			}
+			literal, _ := value.(json.Number)
+			c.observe(inferJSONType(value), string(literal))
+		}
+		for _, c := range columns {
+			if _, ok := row[c.name]; !ok {
+				c.nullable = true
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// decodeNDJSONRow decodes a JSON object, returning its keys in the order they appear.
+// Numbers are decoded as json.Number.
+func decodeNDJSONRow(data []byte) ([]string, map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, nil, errors.New("expected a JSON object")
+	}
+
+	var keys []string
+	row := map[string]any{}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := row[key]; !ok {
+			keys = append(keys, key)
+		}
+		row[key] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, row, nil
+}
+
+// inferJSONType returns the column type of a decoded JSON value, or "" for null
+func inferJSONType(value any) models.ColumnType {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return models.ColumnTypeBoolean
+	case json.Number:
+		return inferNumberType(string(v))
+	case string:
+		// numbers and booleans given as strings are kept as varchar
+		switch t := inferStringType(v, false); t {
+		case models.ColumnTypeTimestamp, models.ColumnTypeVarbinary:
+			return t
+		}
+		return models.ColumnTypeVarchar
+	}
+	// objects and arrays
+	return models.ColumnTypeVarchar
+}
+
+// inferStringType returns the column type of a textual value. Empty values are null when emptyIsNull is set.
+func inferStringType(value string, emptyIsNull bool) models.ColumnType {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if emptyIsNull {
+			return ""
+		}
+		return models.ColumnTypeVarchar
+	}
+	switch strings.ToLower(value) {
+	case "true", "false":
+		return models.ColumnTypeBoolean
+	}
+	if t := inferNumberType(value); t != "" {
+		return t
+	}
+	if hexPattern.MatchString(value) {
+		return models.ColumnTypeVarbinary
+	}
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return models.ColumnTypeTimestamp
+		}
+	}
+	return models.ColumnTypeVarchar
+}
+
+// inferNumberType returns the numeric type of a number literal, or "" if it is not a number
+func inferNumberType(value string) models.ColumnType {
+	if integerPattern.MatchString(value) {
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return models.ColumnTypeInteger
+		}
+		n, _ := new(big.Int).SetString(value, 10)
+		if n.Sign() >= 0 && n.BitLen() <= 256 {
+			return models.ColumnTypeUint256
+		}
+		if n.BitLen() <= 255 {
+			return models.ColumnTypeInt256
+		}
+		return models.ColumnTypeDouble
+	}
+	if decimalPattern.MatchString(value) {
+		return models.ColumnTypeDouble
+	}
+	return ""
+}
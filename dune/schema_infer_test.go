@@ -0,0 +1,94 @@
+package dune
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferSchemaCSV(t *testing.T) {
+	input := `id,price,active,created_at,tx_hash,label,amount,empty
+1,1.5,true,2024-01-01 00:00:00,0xabcd,foo,115792089237316195423570985008687907853269984665640564039457584007913129639935,
+2,2,FALSE,2024-01-02T10:00:00Z,0x01,"bar, baz",1,
+3,,true,2024-01-03,0x,42,2,
+`
+
+	schema, err := InferSchema(strings.NewReader(input), models.ContentTypeCSV, 0)
+
+	require.NoError(t, err)
+	require.Equal(t, []models.UploadsColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "price", Type: "double", Nullable: true},
+		{Name: "active", Type: "boolean"},
+		{Name: "created_at", Type: "timestamp"},
+		{Name: "tx_hash", Type: "varbinary"},
+		{Name: "label", Type: "varchar"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "empty", Type: "varchar", Nullable: true},
+	}, schema)
+}
+
+func TestInferSchemaWideIntegers(t *testing.T) {
+	maxUint256 := "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+	maxInt256 := "57896044618658097711785492504343953926634992332820282019728792003956564819967"
+	input := "unsigned,signed,mixed,negative_small\n" +
+		maxUint256 + ",-" + maxInt256 + "," + maxUint256 + ",-1\n" +
+		"1," + maxInt256 + ",-" + maxInt256 + ",18446744073709551616\n"
+
+	schema, err := InferSchema(strings.NewReader(input), models.ContentTypeCSV, 0)
+
+	require.NoError(t, err)
+	require.Equal(t, []models.UploadsColumn{
+		{Name: "unsigned", Type: "uint256"},
+		{Name: "signed", Type: "int256"},
+		// no 256 bit type holds both values
+		{Name: "mixed", Type: "varchar"},
+		{Name: "negative_small", Type: "int256"},
+	}, schema)
+}
+
+func TestInferSchemaCSVSampleRows(t *testing.T) {
+	input := "value\n1\n2\nnot a number\n"
+
+	schema, err := InferSchema(strings.NewReader(input), models.ContentTypeCSV, 2)
+	require.NoError(t, err)
+	require.Equal(t, "integer", schema[0].Type)
+
+	schema, err = InferSchema(strings.NewReader(input), models.ContentTypeCSV, 3)
+	require.NoError(t, err)
+	require.Equal(t, "varchar", schema[0].Type)
+}
+
+func TestInferSchemaNDJSON(t *testing.T) {
+	input := `{"id": 1, "price": 1, "active": true, "address": "0xabcd", "tags": ["a"]}
+{"id": 2, "price": 2.5, "active": false, "address": "0x1234", "tags": null, "ts": "2024-01-01T00:00:00Z"}
+
+{"id": 3, "price": 3, "active": true, "address": "0x", "tags": [], "ts": "2024-01-02T00:00:00Z", "count": "7"}
+`
+
+	schema, err := InferSchema(strings.NewReader(input), models.ContentTypeNDJSON, 0)
+
+	require.NoError(t, err)
+	require.Equal(t, []models.UploadsColumn{
+		{Name: "id", Type: "integer"},
+		{Name: "price", Type: "double"},
+		{Name: "active", Type: "boolean"},
+		{Name: "address", Type: "varbinary"},
+		{Name: "tags", Type: "varchar", Nullable: true},
+		{Name: "ts", Type: "timestamp", Nullable: true},
+		{Name: "count", Type: "varchar", Nullable: true},
+	}, schema)
+}
+
+func TestInferSchemaErrors(t *testing.T) {
+	_, err := InferSchema(strings.NewReader("a,b\n1\n"), models.ContentTypeCSV, 0)
+	require.ErrorContains(t, err, "line 2: expected 2 fields, got 1")
+
+	_, err = InferSchema(strings.NewReader("{\"a\": 1}\n{oops\n"), models.ContentTypeNDJSON, 0)
+	require.ErrorContains(t, err, "line 2")
+
+	_, err = InferSchema(strings.NewReader("[1, 2]\n"), models.ContentTypeNDJSON, 0)
+	require.ErrorContains(t, err, "line 1: expected a JSON object")
+}
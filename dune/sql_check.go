@@ -0,0 +1,535 @@
+package dune
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// DatasetGetter returns the columns of a dataset by full name. DuneClient and catalog.Catalog implement it.
+type DatasetGetter interface {
+	GetDataset(slug string) (*models.DatasetResponse, error)
+}
+
+// datasetSearcher is implemented by getters able to list datasets offline, such as catalog.Catalog,
+// and is used to suggest the table meant by an unknown name
+type datasetSearcher interface {
+	Search(req models.SearchDatasetsRequest) *models.SearchDatasetsResponse
+}
+
+// TableRef is a schema qualified table read by a query
+type TableRef struct {
+	// Name is the full name of the table, lower cased unless quoted: dex.trades
+	Name string
+	// Alias is the name the table is referred to by in the query, empty if none
+	Alias string
+	Line  int
+}
+
+// ColumnRef is a column identifier of a query
+type ColumnRef struct {
+	// Table is the full name of the table the column belongs to
+	Table string
+	Name  string
+	Line  int
+}
+
+// SQLReferences are the tables and columns a query refers to, as found by AnalyzeSQL
+type SQLReferences struct {
+	Tables []TableRef
+	// Columns are the columns qualified by a table or alias, plus the unqualified ones when the
+	// query reads a single table and nothing else
+	Columns []ColumnRef
+}
+
+// SQLProblem is a table or column of a query not found in the catalog
+type SQLProblem struct {
+	Line  int
+	Table string
+	// Column is empty for unknown tables
+	Column string
+	// Suggestion is the closest existing name, empty if none is close
+	Suggestion string
+}
+
+func (p SQLProblem) String() string {
+	var s string
+	if p.Column == "" {
+		s = fmt.Sprintf("line %d: table %s does not exist", p.Line, p.Table)
+	} else {
+		s = fmt.Sprintf("line %d: column %s does not exist in %s", p.Line, p.Column, p.Table)
+	}
+	if p.Suggestion != "" {
+		s += fmt.Sprintf(", did you mean %s?", p.Suggestion)
+	}
+	return s
+}
+
+// SQLValidationError lists the problems found by ValidateSQL
+type SQLValidationError struct {
+	Problems []SQLProblem
+}
+
+func (e *SQLValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return "invalid SQL: " + strings.Join(lines, "; ")
+}
+
+// ValidateSQL checks the tables and columns referenced by sql against the datasets returned by getter,
+// so typos are caught before SQLExecute spends credits. It returns a *SQLValidationError listing the
+// unknown tables and columns, with the closest existing names as suggestions.
+//
+// Only tables the API reports as not found are unknown: tables that cannot be fetched for another
+// reason, such as datasets missing from an offline catalog, are not checked. The analysis is lexical
+// and skips what it cannot resolve, so a nil error does not guarantee the query runs.
+func ValidateSQL(getter DatasetGetter, sql string) error {
+	refs := AnalyzeSQL(sql)
+
+	var problems []SQLProblem
+	datasets := map[string]*models.DatasetResponse{}
+	for _, table := range refs.Tables {
+		if _, done := datasets[table.Name]; done {
+			continue
+		}
+		dataset, err := getter.GetDataset(table.Name)
+		switch {
+		case err == nil:
+			datasets[table.Name] = dataset
+		case isStatus(err, http.StatusNotFound):
+			datasets[table.Name] = nil
+			problems = append(problems, SQLProblem{
+				Line:       table.Line,
+				Table:      table.Name,
+				Suggestion: suggestTable(getter, table.Name),
+			})
+		default:
+			datasets[table.Name] = nil
+		}
+	}
+
+	reported := map[string]bool{}
+	for _, column := range refs.Columns {
+		dataset := datasets[column.Table]
+		if dataset == nil || len(dataset.Columns) == 0 || reported[column.Table+"."+column.Name] {
+			continue
+		}
+		names := make([]string, len(dataset.Columns))
+		found := false
+		for i, c := range dataset.Columns {
+			names[i] = c.Name
+			found = found || strings.EqualFold(c.Name, column.Name)
+		}
+		if found {
+			continue
+		}
+		reported[column.Table+"."+column.Name] = true
+		problems = append(problems, SQLProblem{
+			Line:       column.Line,
+			Table:      column.Table,
+			Column:     column.Name,
+			Suggestion: closestName(column.Name, names),
+		})
+	}
+
+	if len(problems) > 0 {
+		return &SQLValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// suggestTable returns the closest table of the same schema, when getter can search datasets offline
+func suggestTable(getter DatasetGetter, name string) string {
+	searcher, ok := getter.(datasetSearcher)
+	if !ok {
+		return ""
+	}
+	dot := strings.LastIndex(name, ".")
+	limit := int32(1000)
+	resp := searcher.Search(models.SearchDatasetsRequest{
+		Schemas: []string{name[strings.LastIndex(name[:dot], ".")+1 : dot]},
+		Limit:   &limit,
+	})
+	names := make([]string, len(resp.Results))
+	for i, result := range resp.Results {
+		names[i] = result.FullName
+	}
+	return closestName(name, names)
+}
+
+// closestName returns the candidate with the smallest edit distance to name, if close enough to be a typo
+func closestName(name string, candidates []string) string {
+	name = strings.ToLower(name)
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for _, candidate := range candidates {
+		if d := editDistance(name, strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+type sqlTokenKind int
+
+const (
+	tokenIdent sqlTokenKind = iota
+	tokenQuotedIdent
+	tokenLiteral
+	tokenSymbol
+)
+
+// sqlToken is a token of a query. Identifiers are lower cased unless quoted, and quotes are removed.
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	line int
+}
+
+// keyword reports whether the token is the given keyword
+func (t sqlToken) keyword(words ...string) bool {
+	if t.kind != tokenIdent {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+// value reports whether the token ends an expression, so an identifier after it is an alias
+func (t sqlToken) value() bool {
+	switch t.kind {
+	case tokenIdent:
+		return !sqlKeywords[t.text] || t.text == "end"
+	case tokenSymbol:
+		return t.text == ")" || t.text == "]"
+	}
+	return true
+}
+
+// sqlKeywords are the words never taken for column names
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		all and any array as asc at between bigint boolean both by case cast char cross current
+		current_date current_time current_timestamp date day days decimal desc distinct double else end
+		escape except exists extract false fetch filter first following for from full group having hour
+		hours if ilike in inner int int256 integer intersect interval is join json last lateral left like
+		limit localtime localtimestamp map millisecond minute minutes month months natural next not null
+		nulls of offset on only or order ordinality outer over partition preceding quarter range real
+		recursive right row rows second seconds select smallint some table tablesample then ties time
+		timestamp tinyint to trailing true try_cast uint256 union unbounded unnest using values varbinary
+		varchar week weeks when where window with within year years zone`) {
+		sqlKeywords[word] = true
+	}
+}
+
+// tokenizeSQL splits a query into tokens, dropping comments and whitespace
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	line := 1
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start := i
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(sql[i:], "--"):
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 4
+			}
+			line += strings.Count(sql[i:i+end+4], "\n")
+			i += end + 4
+		case strings.HasPrefix(sql[i:], "{{"):
+			// a query parameter, replaced by a value before execution
+			end := strings.Index(sql[i:], "}}")
+			if end < 0 {
+				end = len(sql) - i - 2
+			}
+			i += end + 2
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: sql[start:i], line: line})
+		case c == '\'' || (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+			text, next := quoted(sql, strings.IndexByte(sql[i:], '\'')+i)
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: text, line: line})
+			line += strings.Count(sql[i:next], "\n")
+			i = next
+		case c == '"':
+			text, next := quoted(sql, i)
+			tokens = append(tokens, sqlToken{kind: tokenQuotedIdent, text: text, line: line})
+			line += strings.Count(sql[i:next], "\n")
+			i = next
+		case isIdentByte(c) && (c < '0' || c > '9'):
+			for i < len(sql) && isIdentByte(sql[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenIdent, text: strings.ToLower(sql[start:i]), line: line})
+		case c >= '0' && c <= '9':
+			for i < len(sql) && (isIdentByte(sql[i]) || sql[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: sql[start:i], line: line})
+		case strings.HasPrefix(sql[i:], "->"):
+			i += 2
+			tokens = append(tokens, sqlToken{kind: tokenSymbol, text: "->", line: line})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: tokenSymbol, text: string(c), line: line})
+		}
+	}
+	return tokens
+}
+
+// quoted reads the text quoted from sql[i] up to the closing quote, a doubled quote escaping it.
+// It returns the unescaped text and the index after the closing quote.
+func quoted(sql string, i int) (string, int) {
+	quote := sql[i]
+	var b strings.Builder
+	for i++; i < len(sql); i++ {
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			return b.String(), i + 1
+		}
+		b.WriteByte(sql[i])
+	}
+	return b.String(), i
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// AnalyzeSQL extracts the schema qualified tables read by a DuneSQL query and the columns
+// referenced through them. The analysis is lexical: strings, comments and query parameters are
+// skipped, CTE names and subqueries are not taken for tables, and identifiers are resolved to a
+// table through its alias or name. Unqualified identifiers are only reported when the query reads
+// a single table, without CTEs, subqueries or lambdas that could define other names.
+func AnalyzeSQL(sql string) SQLReferences {
+	tokens := tokenizeSQL(sql)
+	a := &sqlAnalyzer{
+		tokens:  tokens,
+		ctes:    map[string]bool{},
+		aliases: map[string]bool{},
+		tables:  map[string]string{},
+	}
+	a.findCTEs()
+	a.run()
+	return a.references()
+}
+
+type sqlAnalyzer struct {
+	tokens []sqlToken
+	// ctes are the names defined by WITH
+	ctes map[string]bool
+	// aliases are the names defined with or without AS, which are not columns
+	aliases map[string]bool
+	// tables maps the names a table can be qualified with, its alias, full and table name, to its full name
+	tables map[string]string
+
+	refs SQLReferences
+	// sources counts the relations in FROM clauses, tables or not
+	sources   int
+	lambda    bool
+	qualified []ColumnRef
+	// unqualified are the single identifiers that may be columns
+	unqualified []ColumnRef
+}
+
+// findCTEs collects the names followed by AS (
+func (a *sqlAnalyzer) findCTEs() {
+	for i := 0; i+2 < len(a.tokens); i++ {
+		t := a.tokens[i]
+		if (t.kind == tokenIdent || t.kind == tokenQuotedIdent) && a.tokens[i+1].keyword("as") &&
+			a.tokens[i+2].text == "(" && a.tokens[i+2].kind == tokenSymbol {
+			a.ctes[t.text] = true
+		}
+	}
+}
+
+// name reads a dotted name starting at i, returning its parts and the index after it.
+// A trailing .* is consumed and reported with star.
+func (a *sqlAnalyzer) name(i int) (parts []string, next int, star bool) {
+	for {
+		t := a.tokens[i]
+		parts = append(parts, t.text)
+		i++
+		if i+1 >= len(a.tokens) || a.tokens[i].text != "." || a.tokens[i].kind != tokenSymbol {
+			return parts, i, false
+		}
+		switch a.tokens[i+1].kind {
+		case tokenIdent, tokenQuotedIdent:
+			i++
+		default:
+			return parts, i + 2, a.tokens[i+1].text == "*"
+		}
+	}
+}
+
+func (a *sqlAnalyzer) run() {
+	depth, fromDepth := 0, -1
+	expectTable := false
+	for i := 0; i < len(a.tokens); {
+		t := a.tokens[i]
+		var prev sqlToken
+		if i > 0 {
+			prev = a.tokens[i-1]
+		}
+
+		switch {
+		case t.kind == tokenSymbol:
+			switch t.text {
+			case "(":
+				depth++
+				if expectTable {
+					// a subquery or VALUES list
+					a.sources++
+					expectTable = false
+				}
+			case ")":
+				depth--
+				if depth < fromDepth {
+					fromDepth = -1
+				}
+			case ",":
+				expectTable = depth == fromDepth
+			case "->":
+				a.lambda = true
+			}
+			i++
+			continue
+		case t.kind == tokenLiteral:
+			i++
+			continue
+		case t.keyword("from", "join"):
+			expectTable = true
+			fromDepth = depth
+			i++
+			continue
+		case t.keyword("lateral") && expectTable:
+			i++
+			continue
+		case t.kind == tokenIdent && sqlKeywords[t.text]:
+			if t.keyword("on", "using", "where", "group", "order", "having", "limit", "union", "except",
+				"intersect", "window", "offset", "fetch") && depth == fromDepth {
+				fromDepth = -1
+			}
+			if expectTable {
+				// UNNEST, VALUES or TABLE in place of a table
+				a.sources++
+				expectTable = false
+			}
+			i++
+			continue
+		}
+
+		parts, next, star := a.name(i)
+		followedBy := func(text string) bool {
+			return next < len(a.tokens) && a.tokens[next].kind == tokenSymbol && a.tokens[next].text == text
+		}
+		switch {
+		case expectTable:
+			expectTable = false
+			a.sources++
+			if followedBy("(") || len(parts) < 2 {
+				// a table function, CTE or unqualified table that cannot be checked
+				break
+			}
+			ref := TableRef{Name: strings.Join(parts, "."), Line: t.line}
+			if next+1 < len(a.tokens) && a.tokens[next].keyword("as") {
+				next++
+			}
+			if next < len(a.tokens) && a.isAlias(a.tokens[next]) {
+				ref.Alias = a.tokens[next].text
+				a.aliases[ref.Alias] = true
+				a.tables[ref.Alias] = ref.Name
+				next++
+			}
+			a.tables[ref.Name] = ref.Name
+			if _, taken := a.tables[parts[len(parts)-1]]; !taken {
+				a.tables[parts[len(parts)-1]] = ref.Name
+			}
+			a.refs.Tables = append(a.refs.Tables, ref)
+		case followedBy("(") || star:
+			// a function call, or table.*
+		case followedBy("->"):
+			a.lambda = true
+		case len(parts) == 1 && (prev.keyword("as") || i > 0 && prev.value()):
+			a.aliases[t.text] = true
+		case len(parts) == 1:
+			a.unqualified = append(a.unqualified, ColumnRef{Name: t.text, Line: t.line})
+		default:
+			a.qualified = append(a.qualified, ColumnRef{Table: strings.Join(parts[:len(parts)-1], "."),
+				Name: parts[len(parts)-1], Line: t.line})
+			// the qualifier may also be a table alias with a struct field: t.col.field
+			if len(parts) > 2 {
+				a.qualified = append(a.qualified, ColumnRef{Table: parts[0], Name: parts[1], Line: t.line})
+			}
+		}
+		i = next
+	}
+}
+
+// isAlias reports whether a token following a table name is its alias
+func (a *sqlAnalyzer) isAlias(t sqlToken) bool {
+	return t.kind == tokenQuotedIdent || t.kind == tokenIdent && !sqlKeywords[t.text]
+}
+
+// references resolves the identifiers collected by run to the tables of the query
+func (a *sqlAnalyzer) references() SQLReferences {
+	refs := a.refs
+	for _, column := range a.qualified {
+		if table, ok := a.tables[column.Table]; ok && !a.ctes[column.Table] {
+			column.Table = table
+			refs.Columns = append(refs.Columns, column)
+		}
+	}
+	if a.sources != 1 || len(refs.Tables) != 1 || len(a.ctes) > 0 || a.lambda {
+		return refs
+	}
+	for _, column := range a.unqualified {
+		if !a.aliases[column.Name] && a.tables[column.Name] == "" {
+			column.Table = refs.Tables[0].Name
+			refs.Columns = append(refs.Columns, column)
+		}
+	}
+	return refs
+}
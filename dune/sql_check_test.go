@@ -0,0 +1,101 @@
+package dune
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeSQL(t *testing.T) {
+	refs := AnalyzeSQL(`
+-- daily volume from dex.ignored
+SELECT date_trunc('day', t.block_time) AS day, sum(t.amount_usd) volume, p.symbol
+FROM dex.trades AS t
+JOIN prices.usd p ON p.contract_address = t.token_bought_address /* and prices.ignored */
+WHERE t.blockchain = '{{chain}}' AND t.block_time > now() - interval '7' day
+GROUP BY 1, 3
+ORDER BY volume DESC`)
+
+	require.Equal(t, []TableRef{
+		{Name: "dex.trades", Alias: "t", Line: 4},
+		{Name: "prices.usd", Alias: "p", Line: 5},
+	}, refs.Tables)
+	require.Equal(t, []ColumnRef{
+		{Table: "dex.trades", Name: "block_time", Line: 3},
+		{Table: "dex.trades", Name: "amount_usd", Line: 3},
+		{Table: "prices.usd", Name: "symbol", Line: 3},
+		{Table: "prices.usd", Name: "contract_address", Line: 5},
+		{Table: "dex.trades", Name: "token_bought_address", Line: 5},
+		{Table: "dex.trades", Name: "blockchain", Line: 6},
+		{Table: "dex.trades", Name: "block_time", Line: 6},
+	}, refs.Columns)
+}
+
+func TestAnalyzeSQLUnqualifiedColumns(t *testing.T) {
+	refs := AnalyzeSQL(`SELECT block_time, count(*) AS n, "Hash" FROM ethereum.transactions
+		WHERE "to" = 0xdead AND CAST(value AS double) > 1e18 ORDER BY n`)
+	require.Equal(t, []TableRef{{Name: "ethereum.transactions", Line: 1}}, refs.Tables)
+	var names []string
+	for _, column := range refs.Columns {
+		require.Equal(t, "ethereum.transactions", column.Table)
+		names = append(names, column.Name)
+	}
+	require.Equal(t, []string{"block_time", "Hash", "to", "value"}, names)
+
+	// CTEs, subqueries and lambdas may define names, so unqualified identifiers are not reported
+	for _, sql := range []string{
+		`WITH recent AS (SELECT hash FROM ethereum.transactions) SELECT hash FROM recent`,
+		`SELECT x FROM ethereum.transactions t CROSS JOIN UNNEST(t.logs) AS u(x)`,
+		`SELECT transform(topics, v -> v) FROM ethereum.logs`,
+	} {
+		for _, column := range AnalyzeSQL(sql).Columns {
+			require.NotEqual(t, "x", column.Name, sql)
+			require.NotEqual(t, "v", column.Name, sql)
+			require.NotEqual(t, "recent", column.Table, sql)
+		}
+	}
+}
+
+func TestValidateSQL(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/datasets/dex.trades":
+			json.NewEncoder(w).Encode(models.DatasetResponse{
+				FullName: "dex.trades",
+				Type:     "spell",
+				Columns: []models.DatasetColumn{
+					{Name: "block_time", Type: "timestamp(3) with time zone"},
+					{Name: "amount_usd", Type: "double"},
+				},
+			})
+		default:
+			// not found responses are detected from their status, whatever their body
+			http.NotFound(w, r)
+		}
+	})
+
+	require.NoError(t, ValidateSQL(client, `SELECT block_time, amount_usd FROM dex.trades`))
+
+	err := ValidateSQL(client, `SELECT t.amount_us, t.block_time
+FROM dex.trades t JOIN prices.usd_latest p ON true`)
+	var validationErr *SQLValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.Equal(t, []SQLProblem{
+		{Line: 2, Table: "prices.usd_latest"},
+		{Line: 1, Table: "dex.trades", Column: "amount_us", Suggestion: "amount_usd"},
+	}, validationErr.Problems)
+	require.EqualError(t, err, "invalid SQL: line 2: table prices.usd_latest does not exist; "+
+		"line 1: column amount_us does not exist in dex.trades, did you mean amount_usd?")
+}
+
+func TestValidateSQLSkipsUnavailableDatasets(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "internal error"})
+	})
+	require.NoError(t, ValidateSQL(client, `SELECT nope FROM dex.trades`))
+}
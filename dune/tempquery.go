@@ -0,0 +1,159 @@
+package dune
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+const (
+	// tempQueryNamePrefix starts the name of the queries created by WithTempQuery, which are the only
+	// ones archived by ArchiveStaleTempQueries
+	tempQueryNamePrefix     = "sdk_temp_query_"
+	tempQueryDatetimeLayout = "2006-01-02 15:04:05"
+	listQueriesPageSize     = 100
+)
+
+// WithTempQuery creates a temporary query for sql, executes it with params and hands the execution
+// to fn. The query is archived when WithTempQuery returns, whether fn succeeded, failed or panicked.
+// If ctx is cancelled while fn is running, the execution is cancelled as well.
+func (c *duneClient) WithTempQuery(
+	ctx context.Context, sql string, params map[string]any, fn func(Execution) error,
+) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	createResp, err := c.CreateQuery(models.CreateQueryRequest{
+		Name:       tempQueryNamePrefix + time.Now().UTC().Format("20060102T150405.000000000"),
+		QuerySQL:   sql,
+		IsTemp:     true,
+		IsPrivate:  true,
+		Parameters: tempQueryParameters(params),
+	})
+	if err != nil {
+		return err
+	}
+	queryID := createResp.QueryID
+
+	defer func() {
+		_, archiveErr := c.ArchiveQuery(queryID)
+		if archiveErr != nil && err == nil {
+			err = fmt.Errorf("failed to archive temp query %d: %w", queryID, archiveErr)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	execution, err := c.RunQuery(models.ExecuteRequest{
+		QueryID:         queryID,
+		QueryParameters: tempQueryValues(params),
+	})
+	if err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		execution.Cancel()
+	})
+	defer stop()
+
+	return fn(execution)
+}
+
+// tempQueryParameters declares the parameters of a temp query from the values it will be executed with
+func tempQueryParameters(params map[string]any) []models.QueryParameter {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	out := make([]models.QueryParameter, 0, len(keys))
+	for _, key := range keys {
+		param := models.QueryParameter{
+			Key:   key,
+			Type:  "text",
+			Value: fmt.Sprint(params[key]),
+		}
+		switch v := params[key].(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			param.Type = "number"
+		case time.Time:
+			param.Type = "datetime"
+			param.Value = v.UTC().Format(tempQueryDatetimeLayout)
+		}
+		out = append(out, param)
+	}
+	return out
+}
+
+// tempQueryValues returns the values a temp query is executed with, times being sent in the
+// layout of their datetime parameter declaration
+func tempQueryValues(params map[string]any) map[string]any {
+	if params == nil {
+		return nil
+	}
+	out := make(map[string]any, len(params))
+	for key, value := range params {
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(tempQueryDatetimeLayout)
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// ArchiveStaleTempQueries archives the temporary queries created by WithTempQuery and owned by owner
+// that have not been updated for longer than olderThan, and returns the IDs of the archived queries.
+// Temporary queries created by other means are left alone.
+func (c *duneClient) ArchiveStaleTempQueries(
+	ctx context.Context, owner string, olderThan time.Duration,
+) ([]int, error) {
+	cutoff := time.Now().Add(-olderThan)
+	archived := false
+
+	// collect first: archiving while paginating would shift the offsets of the listing
+	var stale []int
+	req := models.ListQueriesRequest{
+		Limit:    listQueriesPageSize,
+		Owner:    owner,
+		Archived: &archived,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := c.ListQueries(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range resp.Queries {
+			created := q.IsTemp && strings.HasPrefix(q.Name, tempQueryNamePrefix)
+			if created && !q.IsArchived && q.UpdatedAt.Before(cutoff) {
+				stale = append(stale, q.QueryID)
+			}
+		}
+		if resp.NextOffset == nil || len(resp.Queries) == 0 {
+			break
+		}
+		req.Offset = *resp.NextOffset
+	}
+
+	done := make([]int, 0, len(stale))
+	for _, queryID := range stale {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		if _, err := c.ArchiveQuery(queryID); err != nil {
+			return done, fmt.Errorf("failed to archive temp query %d: %w", queryID, err)
+		}
+		done = append(done, queryID)
+	}
+	return done, nil
+}
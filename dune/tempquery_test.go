@@ -0,0 +1,171 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+// tempQueryServer fakes the endpoints used by WithTempQuery and records the calls made
+type tempQueryServer struct {
+	created   []models.CreateQueryRequest
+	executed  []map[string]any
+	archived  []int
+	cancelled int
+	// cancelCalled receives a value for every cancellation, once it has been counted
+	cancelCalled chan struct{}
+}
+
+func (s *tempQueryServer) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/v1/query":
+		var req models.CreateQueryRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.created = append(s.created, req)
+		json.NewEncoder(w).Encode(models.CreateQueryResponse{QueryID: 7})
+	case r.Method == "POST" && r.URL.Path == "/api/v1/query/7/execute":
+		var req models.ExecuteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		s.executed = append(s.executed, req.QueryParameters)
+		json.NewEncoder(w).Encode(models.ExecuteResponse{
+			ExecutionID: "01ABCDEFGHIJKLMNOPQRSTUVWX",
+			State:       "QUERY_STATE_PENDING",
+		})
+	case r.Method == "POST" && r.URL.Path == "/api/v1/execution/01ABCDEFGHIJKLMNOPQRSTUVWX/cancel":
+		s.cancelled++
+		json.NewEncoder(w).Encode(models.CancelResponse{Success: true})
+		if s.cancelCalled != nil {
+			s.cancelCalled <- struct{}{}
+		}
+	case r.Method == "POST" && r.URL.Path == "/api/v1/query/7/archive":
+		s.archived = append(s.archived, 7)
+		json.NewEncoder(w).Encode(models.UpdateQueryResponse{QueryID: 7})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+	}
+}
+
+func TestWithTempQuery(t *testing.T) {
+	server := &tempQueryServer{}
+	client := newTestClient(t, server.handle)
+
+	var gotID string
+	err := client.WithTempQuery(context.Background(), "SELECT {{n}}", map[string]any{"n": 3}, func(e Execution) error {
+		gotID = e.GetID()
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "01ABCDEFGHIJKLMNOPQRSTUVWX", gotID)
+	require.Len(t, server.created, 1)
+	require.True(t, server.created[0].IsTemp)
+	require.Equal(t, "SELECT {{n}}", server.created[0].QuerySQL)
+	require.Equal(t, []models.QueryParameter{{Key: "n", Type: "number", Value: "3"}}, server.created[0].Parameters)
+	require.Equal(t, []map[string]any{{"n": float64(3)}}, server.executed)
+	require.Equal(t, []int{7}, server.archived)
+	require.Equal(t, 0, server.cancelled)
+}
+
+func TestWithTempQueryDatetimeParameter(t *testing.T) {
+	server := &tempQueryServer{}
+	client := newTestClient(t, server.handle)
+	since := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	err := client.WithTempQuery(context.Background(), "SELECT {{since}}", map[string]any{"since": since},
+		func(Execution) error { return nil })
+
+	require.NoError(t, err)
+	// the value is sent in the layout the parameter is declared with
+	require.Equal(t, []models.QueryParameter{{Key: "since", Type: "datetime", Value: "2024-05-01 10:30:00"}},
+		server.created[0].Parameters)
+	require.Equal(t, []map[string]any{{"since": "2024-05-01 10:30:00"}}, server.executed)
+}
+
+func TestWithTempQueryArchivesOnError(t *testing.T) {
+	server := &tempQueryServer{}
+	client := newTestClient(t, server.handle)
+	errCallback := errors.New("callback failed")
+
+	err := client.WithTempQuery(context.Background(), "SELECT 1", nil, func(Execution) error {
+		return errCallback
+	})
+
+	require.ErrorIs(t, err, errCallback)
+	require.Equal(t, []int{7}, server.archived)
+}
+
+func TestWithTempQueryArchivesOnPanic(t *testing.T) {
+	server := &tempQueryServer{}
+	client := newTestClient(t, server.handle)
+
+	require.Panics(t, func() {
+		client.WithTempQuery(context.Background(), "SELECT 1", nil, func(Execution) error {
+			panic("boom")
+		})
+	})
+	require.Equal(t, []int{7}, server.archived)
+}
+
+func TestWithTempQueryCancelsOnContextDone(t *testing.T) {
+	server := &tempQueryServer{cancelCalled: make(chan struct{}, 1)}
+	client := newTestClient(t, server.handle)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := client.WithTempQuery(ctx, "SELECT 1", nil, func(Execution) error {
+		cancel()
+		// wait for the cancellation callback to reach the server
+		<-server.cancelCalled
+		return ctx.Err()
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, server.cancelled)
+	require.Equal(t, []int{7}, server.archived)
+}
+
+func TestArchiveStaleTempQueries(t *testing.T) {
+	now := time.Now()
+	var gotOffsets []string
+	var archived []string
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/queries":
+			gotOffsets = append(gotOffsets, r.URL.Query().Get("offset"))
+			resp := models.ListQueriesResponse{}
+			if r.URL.Query().Get("offset") == "" {
+				next := 2
+				resp.NextOffset = &next
+				resp.Queries = []models.QueryListElement{
+					{QueryID: 1, Name: "sdk_temp_query_1", IsTemp: true, UpdatedAt: now.Add(-48 * time.Hour)},
+					{QueryID: 2, Name: "sdk_temp_query_2", IsTemp: false, UpdatedAt: now.Add(-48 * time.Hour)},
+					// temp queries not created by WithTempQuery are left alone
+					{QueryID: 5, Name: "scratch", IsTemp: true, UpdatedAt: now.Add(-48 * time.Hour)},
+				}
+			} else {
+				resp.Queries = []models.QueryListElement{
+					{QueryID: 3, Name: "sdk_temp_query_3", IsTemp: true, UpdatedAt: now.Add(-time.Minute)},
+					{QueryID: 4, Name: "sdk_temp_query_4", IsTemp: true, UpdatedAt: now.Add(-25 * time.Hour)},
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case r.Method == "POST":
+			archived = append(archived, r.URL.Path)
+			json.NewEncoder(w).Encode(models.UpdateQueryResponse{})
+		}
+	})
+
+	ids, err := client.ArchiveStaleTempQueries(context.Background(), "alice", 24*time.Hour)
+
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 4}, ids)
+	require.Equal(t, []string{"", "2"}, gotOffsets)
+	require.Equal(t, []string{"/api/v1/query/1/archive", "/api/v1/query/4/archive"}, archived)
+}
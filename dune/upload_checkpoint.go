@@ -0,0 +1,211 @@
+package dune
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/duneanalytics/duneapi-client-go/internal/fileutil"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// UploadCheckpoint records the progress of InsertIntoUploadResumable. It is saved after every
+// chunk written to the table, so an interrupted upload can resume after the last written chunk.
+type UploadCheckpoint struct {
+	Namespace     string `json:"namespace"`
+	TableName     string `json:"table_name"`
+	ContentType   string `json:"content_type"`
+	MaxChunkBytes int    `json:"max_chunk_bytes"`
+	// Offset is the input offset right after the last written chunk
+	Offset       int64 `json:"offset"`
+	RowsWritten  int64 `json:"rows_written"`
+	BytesWritten int64 `json:"bytes_written"`
+	// Complete is set once the whole input has been written
+	Complete bool              `json:"complete"`
+	Chunks   []CheckpointChunk `json:"chunks"`
+}
+
+// CheckpointChunk is a chunk of the input written to the table
+type CheckpointChunk struct {
+	Index       int   `json:"index"`
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"`
+	FirstLine   int   `json:"first_line"`
+	Rows        int   `json:"rows"`
+	// SHA256 is the hex encoded hash of the request body, used to detect input changes when resuming
+	SHA256       string `json:"sha256"`
+	RowsWritten  int64  `json:"rows_written"`
+	BytesWritten int64  `json:"bytes_written"`
+}
+
+// LoadUploadCheckpoint reads a checkpoint saved by InsertIntoUploadResumable
+func LoadUploadCheckpoint(path string) (*UploadCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint UploadCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	return &checkpoint, nil
+}
+
+func (cp *UploadCheckpoint) save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(path, data, 0o644)
+}
+
+func (cp *UploadCheckpoint) response() *models.UploadsInsertResponse {
+	return &models.UploadsInsertResponse{
+		Name:         cp.TableName,
+		RowsWritten:  cp.RowsWritten,
+		BytesWritten: cp.BytesWritten,
+	}
+}
+
+func chunkHash(chunk *insertChunk) string {
+	sum := sha256.Sum256(chunk.data)
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkerAt returns a chunker reading r from offset, line being the number of input lines before offset.
+// For CSV input the header is still read from the start of r.
+func chunkerAt(
+	r io.ReadSeeker, contentType string, maxBytes int, offset int64, line int,
+) (*chunker, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	chunks, err := newChunker(r, contentType, maxBytes)
+	if err != nil || offset == 0 {
+		return chunks, err
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	chunks.rows.r.Reset(r)
+	chunks.rows.offset, chunks.rows.line = offset, line
+	return chunks, nil
+}
+
+// InsertIntoUploadResumable inserts CSV or NDJSON rows from r like InsertIntoUploadReader, recording
+// an UploadCheckpoint in checkpointPath after every chunk written. When checkpointPath already holds
+// a checkpoint for the same table, the upload resumes after its last written chunk: that chunk is read
+// again and its hash compared with the checkpoint, to make sure r is the input the checkpoint was made
+// for. The chunk size of the checkpoint is kept when resuming, and chunks are always sent sequentially.
+// A checkpoint marked Complete makes the call a no-op returning the totals of the checkpoint.
+//
+// Delivery is at least once: a chunk is recorded only after Dune acknowledged it, so if the process
+// stops between the acknowledgement and the checkpoint being saved, or the response is lost because
+// ctx was cancelled while the chunk was in flight, the chunk is sent again on resume and its rows are
+// duplicated. Uploads that must not hold duplicates should carry a key and be deduplicated afterwards,
+// or be inserted with UpsertIntoUpload.
+func (c *duneClient) InsertIntoUploadResumable(
+	ctx context.Context, namespace, tableName string, r io.ReadSeeker, contentType, checkpointPath string,
+	options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	checkpoint, err := LoadUploadCheckpoint(checkpointPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		maxBytes := options.MaxChunkBytes
+		if maxBytes <= 0 {
+			maxBytes = models.DefaultInsertChunkBytes
+		}
+		checkpoint = &UploadCheckpoint{
+			Namespace:     namespace,
+			TableName:     tableName,
+			ContentType:   contentType,
+			MaxChunkBytes: maxBytes,
+		}
+	case err != nil:
+		return nil, err
+	case checkpoint.Namespace != namespace || checkpoint.TableName != tableName ||
+		checkpoint.ContentType != contentType:
+		return nil, fmt.Errorf("checkpoint %s was made for a %s upload into %s.%s",
+			checkpointPath, checkpoint.ContentType, checkpoint.Namespace, checkpoint.TableName)
+	case checkpoint.Complete:
+		return checkpoint.response(), nil
+	}
+
+	if options.Validate {
+		// r is seekable, so validating it reads it in place and rewinds it
+		if _, _, err := c.validateInput(ctx, namespace, tableName, r, contentType); err != nil {
+			return nil, err
+		}
+	}
+
+	var chunks *chunker
+	if n := len(checkpoint.Chunks); n > 0 {
+		last := checkpoint.Chunks[n-1]
+		chunks, err = chunkerAt(r, contentType, checkpoint.MaxChunkBytes, last.StartOffset, last.FirstLine-1)
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := chunks.next()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == io.EOF || chunkHash(chunk) != last.SHA256 || chunk.endOffset != last.EndOffset {
+			return nil, fmt.Errorf("input does not match checkpoint %s at chunk %d starting at line %d",
+				checkpointPath, last.Index, last.FirstLine)
+		}
+		chunks.index = last.Index + 1
+	} else {
+		chunks, err = chunkerAt(r, contentType, checkpoint.MaxChunkBytes, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return checkpoint.response(), err
+		}
+		chunk, err := chunks.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return checkpoint.response(), err
+		}
+
+		resp, err := c.insertIntoUpload(ctx, namespace, tableName, string(chunk.data), contentType)
+		if err != nil {
+			return checkpoint.response(), fmt.Errorf("chunk %d starting at line %d: %w",
+				chunk.index, chunk.firstLine, err)
+		}
+		checkpoint.Chunks = append(checkpoint.Chunks, CheckpointChunk{
+			Index:        chunk.index,
+			StartOffset:  chunk.startOffset,
+			EndOffset:    chunk.endOffset,
+			FirstLine:    chunk.firstLine,
+			Rows:         chunk.rows,
+			SHA256:       chunkHash(chunk),
+			RowsWritten:  resp.RowsWritten,
+			BytesWritten: resp.BytesWritten,
+		})
+		checkpoint.Offset = chunk.endOffset
+		checkpoint.RowsWritten += resp.RowsWritten
+		checkpoint.BytesWritten += resp.BytesWritten
+		if err := checkpoint.save(checkpointPath); err != nil {
+			return checkpoint.response(), fmt.Errorf("chunk %d was written but saving the checkpoint failed: %w",
+				chunk.index, err)
+		}
+	}
+
+	checkpoint.Complete = true
+	if err := checkpoint.save(checkpointPath); err != nil {
+		return checkpoint.response(), err
+	}
+	return checkpoint.response(), nil
+}
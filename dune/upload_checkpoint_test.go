@@ -0,0 +1,101 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertIntoUploadResumable(t *testing.T) {
+	var bodies []string
+	failAt := 3
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(bodies)+1 == failAt {
+			failAt = 0
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "unavailable"})
+			return
+		}
+		bodies = append(bodies, string(body))
+		json.NewEncoder(w).Encode(models.UploadsInsertResponse{
+			RowsWritten:  int64(strings.Count(string(body), ",") - 1),
+			BytesWritten: int64(len(body)),
+		})
+	})
+
+	path := filepath.Join(t.TempDir(), "upload.checkpoint.json")
+	input := "id,name\n1,a\n2,b\n\n3,\"c\nc\"\n4,d\n5,e\n"
+	options := models.InsertOptions{MaxChunkBytes: 16}
+
+	resp, err := client.InsertIntoUploadResumable(context.Background(), "ns", "table",
+		strings.NewReader(input), models.ContentTypeCSV, path, options)
+	require.ErrorContains(t, err, "chunk 2 starting at line 7")
+	require.Equal(t, int64(3), resp.RowsWritten)
+
+	checkpoint, err := LoadUploadCheckpoint(path)
+	require.NoError(t, err)
+	require.Len(t, checkpoint.Chunks, 2)
+	require.False(t, checkpoint.Complete)
+	require.Equal(t, int64(strings.Index(input, "4,d")), checkpoint.Offset)
+
+	// a different input is refused
+	_, err = client.InsertIntoUploadResumable(context.Background(), "ns", "table",
+		strings.NewReader(strings.Replace(input, "3,\"c", "3,\"x", 1)), models.ContentTypeCSV, path, options)
+	require.ErrorContains(t, err, "input does not match checkpoint")
+	_, err = client.InsertIntoUploadResumable(context.Background(), "ns", "other",
+		strings.NewReader(input), models.ContentTypeCSV, path, options)
+	require.ErrorContains(t, err, "was made for a text/csv upload into ns.table")
+
+	// the chunk size of the checkpoint wins over the options
+	resp, err = client.InsertIntoUploadResumable(context.Background(), "ns", "table",
+		strings.NewReader(input), models.ContentTypeCSV, path, models.InsertOptions{MaxChunkBytes: 1024})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.RowsWritten)
+	require.Equal(t, []string{
+		"id,name\n1,a\n2,b\n",
+		"id,name\n3,\"c\nc\"\n",
+		"id,name\n4,d\n5,e\n",
+	}, bodies)
+
+	checkpoint, err = LoadUploadCheckpoint(path)
+	require.NoError(t, err)
+	require.True(t, checkpoint.Complete)
+	require.Len(t, checkpoint.Chunks, 3)
+	require.Equal(t, 2, checkpoint.Chunks[2].Index)
+	require.Equal(t, 7, checkpoint.Chunks[2].FirstLine)
+	require.Equal(t, int64(len(input)), checkpoint.Offset)
+
+	// a complete upload is not sent again
+	resp, err = client.InsertIntoUploadResumable(context.Background(), "ns", "table",
+		strings.NewReader(input), models.ContentTypeCSV, path, options)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), resp.RowsWritten)
+	require.Len(t, bodies, 3)
+}
+
+func TestInsertIntoUploadResumableCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	})
+	path := filepath.Join(t.TempDir(), "upload.checkpoint.json")
+
+	_, err := client.InsertIntoUploadResumable(ctx, "ns", "table",
+		strings.NewReader("id\n1\n"), models.ContentTypeCSV, path, models.InsertOptions{})
+
+	require.ErrorIs(t, err, context.Canceled)
+	// the chunk in flight is not recorded, it is sent again on resume
+	_, err = LoadUploadCheckpoint(path)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
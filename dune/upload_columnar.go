@@ -0,0 +1,265 @@
+package dune
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// RecordBatch is a batch of rows in columnar form. The arrowupload package adapts Arrow records,
+// Arrow IPC streams and Parquet files to it, so that this package does not depend on Arrow; other
+// columnar sources can implement it, typing their columns with ArrowColumnType or ParquetColumnType.
+type RecordBatch interface {
+	// Schema returns the columns of the batch
+	Schema() []models.UploadsColumn
+	// NumRows returns the number of rows of the batch
+	NumRows() int
+	// Value returns the value of a column in a row, nil for null. Values are Go scalars, strings,
+	// []byte, time.Time, *big.Int, or slices and maps stored as JSON.
+	Value(column, row int) any
+}
+
+// RecordBatchSchema returns the table schema shared by batches, or an error when they differ
+func RecordBatchSchema(batches ...RecordBatch) ([]models.UploadsColumn, error) {
+	if len(batches) == 0 {
+		return nil, errors.New("no record batches")
+	}
+	schema := batches[0].Schema()
+	for i, batch := range batches[1:] {
+		if err := mergeBatchSchema(schema, batch.Schema()); err != nil {
+			return nil, fmt.Errorf("batch %d: %w", i+1, err)
+		}
+	}
+	return schema, nil
+}
+
+// mergeBatchSchema checks that the columns of a batch are those of schema, and marks the columns
+// nullable in the batch as nullable in schema
+func mergeBatchSchema(schema, other []models.UploadsColumn) error {
+	if len(other) != len(schema) {
+		return fmt.Errorf("%d columns, expected %d", len(other), len(schema))
+	}
+	for j := range schema {
+		if other[j].Name != schema[j].Name || other[j].Type != schema[j].Type {
+			return fmt.Errorf("column %d is %s %s, expected %s %s",
+				j, other[j].Name, other[j].Type, schema[j].Name, schema[j].Type)
+		}
+		// a column is nullable if it is in any batch
+		schema[j].Nullable = schema[j].Nullable || other[j].Nullable
+	}
+	return nil
+}
+
+// writeRecordBatch writes the rows of a batch to w as NDJSON
+func writeRecordBatch(w io.Writer, batch RecordBatch) error {
+	schema := batch.Schema()
+	row := make(map[string]any, len(schema))
+	encoder := json.NewEncoder(w)
+	for i := 0; i < batch.NumRows(); i++ {
+		for j, column := range schema {
+			value, err := columnarValue(batch.Value(j, i))
+			if err != nil {
+				return fmt.Errorf("row %d, column %s: %w", i, column.Name, err)
+			}
+			row[column.Name] = value
+		}
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// columnarValue converts a value of a RecordBatch to the JSON value expected by Dune
+func columnarValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint8, uint16, uint32, float32, float64:
+		return v, nil
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10)), nil
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		return "0x" + hex.EncodeToString(v), nil
+	case time.Time:
+		return v.UTC().Format(timestampLayout), nil
+	case *big.Int:
+		if v == nil {
+			return nil, nil
+		}
+		return json.Number(v.String()), nil
+	case big.Int:
+		return json.Number(v.String()), nil
+	}
+	return uploadValue(reflect.ValueOf(value))
+}
+
+// RecordBatchReader yields record batches one at a time, such as the batches read from a file.
+// Next returns io.EOF after the last batch. A batch is only used until Next is called again.
+type RecordBatchReader interface {
+	Next() (RecordBatch, error)
+}
+
+// recordBatchSlice is a RecordBatchReader over batches held in memory
+type recordBatchSlice []RecordBatch
+
+func (s *recordBatchSlice) Next() (RecordBatch, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	batch := (*s)[0]
+	*s = (*s)[1:]
+	return batch, nil
+}
+
+// InsertRecordBatches inserts columnar batches into an existing table. The API accepts neither
+// Parquet nor Arrow, so the batches are converted to NDJSON and streamed with InsertIntoUploadReader.
+func (c *duneClient) InsertRecordBatches(
+	ctx context.Context, namespace, tableName string, batches []RecordBatch, options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	if _, err := RecordBatchSchema(batches...); err != nil {
+		return nil, err
+	}
+	reader := recordBatchSlice(batches)
+	return InsertRecordBatchReader(ctx, c, namespace, tableName, &reader, options)
+}
+
+// InsertRecordBatchReader inserts the batches of a RecordBatchReader into an existing table as they
+// are read, converted to NDJSON and streamed with InsertIntoUploadReader, so the batches of a large
+// file are never all held in memory. Every batch must have the columns of the first one.
+func InsertRecordBatchReader(
+	ctx context.Context, client DuneClient, namespace, tableName string, batches RecordBatchReader,
+	options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	r, w := io.Pipe()
+	go func() {
+		buf := bufio.NewWriter(w)
+		var schema []models.UploadsColumn
+		for i := 0; ; i++ {
+			batch, err := batches.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(fmt.Errorf("batch %d: %w", i, err))
+				return
+			}
+			if schema == nil {
+				schema = batch.Schema()
+			} else if err := mergeBatchSchema(schema, batch.Schema()); err != nil {
+				w.CloseWithError(fmt.Errorf("batch %d: %w", i, err))
+				return
+			}
+			if err := writeRecordBatch(buf, batch); err != nil {
+				w.CloseWithError(fmt.Errorf("batch %d: %w", i, err))
+				return
+			}
+		}
+		w.CloseWithError(buf.Flush())
+	}()
+	// unblocks the writer when the insert stops before reading everything
+	defer r.Close()
+
+	return client.InsertIntoUploadReader(ctx, namespace, tableName, r, models.ContentTypeNDJSON, options)
+}
+
+// ArrowColumnType maps an Arrow data type, as printed by arrow.DataType.String(), to a Dune
+// column type. Decimals without a scale map to integer or int256, other decimals to double.
+// Nested types (lists, structs, maps) and types without a Dune equivalent are stored as JSON
+// in varchar columns.
+func ArrowColumnType(arrowType string) models.ColumnType {
+	arrowType = strings.ToLower(strings.TrimSpace(arrowType))
+	name, params, _ := strings.Cut(arrowType, "(")
+	name, _, _ = strings.Cut(name, "[")
+	name, _, _ = strings.Cut(name, "<")
+
+	switch name {
+	case "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32":
+		return models.ColumnTypeInteger
+	case "uint64":
+		return models.ColumnTypeUint256
+	case "float16", "float32", "float64", "halffloat", "float", "double":
+		return models.ColumnTypeDouble
+	case "bool", "boolean":
+		return models.ColumnTypeBoolean
+	case "binary", "large_binary", "binary_view", "fixed_size_binary":
+		return models.ColumnTypeVarbinary
+	case "date32", "date64", "timestamp":
+		return models.ColumnTypeTimestamp
+	case "decimal", "decimal32", "decimal64", "decimal128", "decimal256":
+		return decimalColumnType(params)
+	}
+	return models.ColumnTypeVarchar
+}
+
+// ParquetColumnType maps a Parquet physical type and its logical or converted type annotation,
+// which may be empty, to a Dune column type. Logical types are given as printed by Parquet tools:
+// STRING, DATE, TIMESTAMP(MILLIS,true), DECIMAL(38,0), INT(64,false), UINT_64...
+func ParquetColumnType(physicalType, logicalType string) models.ColumnType {
+	logicalType = strings.ToUpper(strings.TrimSpace(logicalType))
+	name, params, _ := strings.Cut(logicalType, "(")
+
+	switch name {
+	case "STRING", "UTF8", "ENUM", "JSON", "BSON", "UUID", "LIST", "MAP", "MAP_KEY_VALUE":
+		return models.ColumnTypeVarchar
+	case "DATE", "TIMESTAMP", "TIMESTAMP_MILLIS", "TIMESTAMP_MICROS":
+		return models.ColumnTypeTimestamp
+	case "DECIMAL":
+		return decimalColumnType(params)
+	case "UINT_64":
+		return models.ColumnTypeUint256
+	case "INT":
+		// INT(bitWidth,isSigned)
+		if strings.ReplaceAll(params, " ", "") == "64,FALSE)" {
+			return models.ColumnTypeUint256
+		}
+		return models.ColumnTypeInteger
+	case "INT_8", "INT_16", "INT_32", "INT_64", "UINT_8", "UINT_16", "UINT_32":
+		return models.ColumnTypeInteger
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(physicalType)) {
+	case "BOOLEAN":
+		return models.ColumnTypeBoolean
+	case "INT32", "INT64":
+		return models.ColumnTypeInteger
+	case "INT96":
+		// legacy timestamps
+		return models.ColumnTypeTimestamp
+	case "FLOAT", "DOUBLE":
+		return models.ColumnTypeDouble
+	case "BYTE_ARRAY", "FIXED_LEN_BYTE_ARRAY":
+		return models.ColumnTypeVarbinary
+	}
+	return models.ColumnTypeVarchar
+}
+
+// decimalColumnType maps the "precision, scale)" parameters of a decimal type to a column type
+func decimalColumnType(params string) models.ColumnType {
+	precision, scale, _ := strings.Cut(strings.TrimSuffix(strings.TrimSpace(params), ")"), ",")
+	p, err := strconv.Atoi(strings.TrimSpace(precision))
+	if err != nil {
+		return models.ColumnTypeDouble
+	}
+	if s := strings.TrimSpace(scale); s != "" && s != "0" {
+		return models.ColumnTypeDouble
+	}
+	if p <= 18 {
+		return models.ColumnTypeInteger
+	}
+	return models.ColumnTypeInt256
+}
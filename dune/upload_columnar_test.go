@@ -0,0 +1,116 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+// testBatch is a RecordBatch holding its columns as slices
+type testBatch struct {
+	schema  []models.UploadsColumn
+	columns [][]any
+}
+
+func (b testBatch) Schema() []models.UploadsColumn {
+	return append([]models.UploadsColumn{}, b.schema...)
+}
+func (b testBatch) NumRows() int              { return len(b.columns[0]) }
+func (b testBatch) Value(column, row int) any { return b.columns[column][row] }
+
+func TestInsertRecordBatches(t *testing.T) {
+	var body string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 3})
+	})
+
+	schema := []models.UploadsColumn{
+		{Name: "block_time", Type: string(ArrowColumnType("timestamp[us, tz=UTC]"))},
+		{Name: "hash", Type: string(ArrowColumnType("fixed_size_binary[32]"))},
+		{Name: "value", Type: string(ArrowColumnType("decimal128(38, 0)")), Nullable: true},
+		{Name: "tags", Type: string(ArrowColumnType("list<item: utf8, nullable>")), Nullable: true},
+	}
+	batches := []RecordBatch{
+		testBatch{schema: schema, columns: [][]any{
+			{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{[]byte{0xab}, []byte{0xcd}},
+			{new(big.Int).Lsh(big.NewInt(1), 100), nil},
+			{[]string{"a", "b"}, nil},
+		}},
+		testBatch{schema: schema, columns: [][]any{
+			{time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}, {[]byte{0xef}}, {uint64(1)}, {[]string{}},
+		}},
+	}
+
+	resp, err := client.InsertRecordBatches(context.Background(), "ns", "t", batches, models.InsertOptions{})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3), resp.RowsWritten)
+	require.Equal(t, `{"block_time":"2024-01-01 00:00:00.000","hash":"0xab","tags":"[\"a\",\"b\"]",`+
+		`"value":1267650600228229401496703205376}
+{"block_time":"2024-01-02 00:00:00.000","hash":"0xcd","tags":null,"value":null}
+{"block_time":"2024-01-03 00:00:00.000","hash":"0xef","tags":"[]","value":1}
+`, body)
+}
+
+func TestRecordBatchSchema(t *testing.T) {
+	a := testBatch{schema: []models.UploadsColumn{{Name: "id", Type: "integer"}}}
+	b := testBatch{schema: []models.UploadsColumn{{Name: "id", Type: "integer", Nullable: true}}}
+	c := testBatch{schema: []models.UploadsColumn{{Name: "id", Type: "varchar"}}}
+
+	schema, err := RecordBatchSchema(a, b)
+	require.NoError(t, err)
+	require.Equal(t, []models.UploadsColumn{{Name: "id", Type: "integer", Nullable: true}}, schema)
+
+	_, err = RecordBatchSchema(a, c)
+	require.ErrorContains(t, err, "batch 1: column 0 is id varchar, expected id integer")
+}
+
+func TestArrowColumnType(t *testing.T) {
+	for arrowType, expected := range map[string]string{
+		"int32":                   "integer",
+		"uint64":                  "uint256",
+		"float64":                 "double",
+		"bool":                    "boolean",
+		"utf8":                    "varchar",
+		"large_binary":            "varbinary",
+		"date32":                  "timestamp",
+		"timestamp[ms]":           "timestamp",
+		"decimal(10, 0)":          "integer",
+		"decimal256(76, 0)":       "int256",
+		"decimal128(38, 18)":      "double",
+		"struct<a: int64>":        "varchar",
+		"map<utf8, int64>":        "varchar",
+		"dictionary<values=utf8>": "varchar",
+	} {
+		require.Equal(t, models.ColumnType(expected), ArrowColumnType(arrowType), arrowType)
+	}
+}
+
+func TestParquetColumnType(t *testing.T) {
+	for _, tc := range []struct{ physical, logical, expected string }{
+		{"INT64", "", "integer"},
+		{"INT64", "INT(64,false)", "uint256"},
+		{"INT64", "UINT_64", "uint256"},
+		{"INT64", "TIMESTAMP(MICROS,true)", "timestamp"},
+		{"INT96", "", "timestamp"},
+		{"INT32", "DATE", "timestamp"},
+		{"BYTE_ARRAY", "", "varbinary"},
+		{"BYTE_ARRAY", "STRING", "varchar"},
+		{"FIXED_LEN_BYTE_ARRAY", "DECIMAL(38,0)", "int256"},
+		{"DOUBLE", "", "double"},
+		{"BOOLEAN", "", "boolean"},
+	} {
+		require.Equal(t, models.ColumnType(tc.expected), ParquetColumnType(tc.physical, tc.logical),
+			tc.physical+" "+tc.logical)
+	}
+}
@@ -0,0 +1,281 @@
+package dune
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// rowReader reads CSV or NDJSON input one row at a time. CSV rows may span several
+// lines when a quoted field contains a newline, so quotes are tracked across lines.
+type rowReader struct {
+	r   *bufio.Reader
+	csv bool
+	// offset is the number of bytes consumed so far, line the number of lines
+	offset int64
+	line   int
+	// rowOffset is the offset of the last row returned by next
+	rowOffset int64
+}
+
+func newRowReader(r io.Reader, contentType string) (*rowReader, error) {
+	switch contentType {
+	case models.ContentTypeCSV, models.ContentTypeNDJSON:
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, use %s or %s",
+			contentType, models.ContentTypeCSV, models.ContentTypeNDJSON)
+	}
+	return &rowReader{
+		r:   bufio.NewReader(r),
+		csv: contentType == models.ContentTypeCSV,
+	}, nil
+}
+
+// next returns the next non blank row, always terminated by a newline, and the line it starts on.
+// It returns io.EOF once the input is exhausted.
+func (rr *rowReader) next() ([]byte, int, error) {
+	for {
+		var row []byte
+		startLine, startOffset := rr.line+1, rr.offset
+		inQuotes := false
+		for {
+			line, err := rr.r.ReadBytes('\n')
+			rr.offset += int64(len(line))
+			if len(line) > 0 {
+				rr.line++
+			}
+			row = append(row, line...)
+			if rr.csv && bytes.Count(line, []byte{'"'})%2 == 1 {
+				inQuotes = !inQuotes
+			}
+			if err == io.EOF {
+				if inQuotes {
+					return nil, startLine, fmt.Errorf("line %d: unterminated quoted field", startLine)
+				}
+				break
+			}
+			if err != nil {
+				return nil, startLine, err
+			}
+			if !inQuotes {
+				break
+			}
+		}
+
+		if len(bytes.TrimSpace(row)) == 0 {
+			if len(row) == 0 {
+				return nil, startLine, io.EOF
+			}
+			continue
+		}
+		if row[len(row)-1] != '\n' {
+			row = append(row, '\n')
+		}
+		rr.rowOffset = startOffset
+		return row, startLine, nil
+	}
+}
+
+// insertChunk is a group of consecutive rows sent in a single InsertIntoUpload request
+type insertChunk struct {
+	index int
+	data  []byte
+	rows  int
+	// firstLine is the input line of the first row, startOffset and endOffset delimit the rows in the input
+	firstLine   int
+	startOffset int64
+	endOffset   int64
+}
+
+// chunker groups the rows of a rowReader into size capped chunks. For CSV input the
+// header row is repeated at the top of every chunk.
+type chunker struct {
+	rows     *rowReader
+	maxBytes int
+	header   []byte
+	// pending holds a row read but not fitting in the previous chunk, with its position in the input
+	pending          []byte
+	pendingLine      int
+	pendingOffset    int64
+	pendingEndOffset int64
+	index            int
+}
+
+func newChunker(r io.Reader, contentType string, maxBytes int) (*chunker, error) {
+	rows, err := newRowReader(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes <= 0 {
+		maxBytes = models.DefaultInsertChunkBytes
+	}
+	c := &chunker{rows: rows, maxBytes: maxBytes}
+	if rows.csv {
+		header, _, err := rows.next()
+		if err == io.EOF {
+			return nil, errors.New("missing CSV header")
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.header = header
+	}
+	return c, nil
+}
+
+func (c *chunker) next() (*insertChunk, error) {
+	chunk := &insertChunk{
+		index: c.index,
+		data:  append([]byte{}, c.header...),
+	}
+	for {
+		row, line, offset, endOffset := c.pending, c.pendingLine, c.pendingOffset, c.pendingEndOffset
+		c.pending = nil
+		if row == nil {
+			var err error
+			row, line, err = c.rows.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			offset, endOffset = c.rows.rowOffset, c.rows.offset
+		}
+
+		if len(c.header)+len(row) > c.maxBytes {
+			return nil, fmt.Errorf("line %d: row of %d bytes does not fit in chunks of %d bytes", line, len(row), c.maxBytes)
+		}
+		if chunk.rows > 0 && len(chunk.data)+len(row) > c.maxBytes {
+			c.pending, c.pendingLine, c.pendingOffset, c.pendingEndOffset = row, line, offset, endOffset
+			break
+		}
+		if chunk.rows == 0 {
+			chunk.firstLine = line
+			chunk.startOffset = offset
+		}
+		chunk.data = append(chunk.data, row...)
+		chunk.rows++
+		chunk.endOffset = endOffset
+	}
+
+	if chunk.rows == 0 {
+		return nil, io.EOF
+	}
+	c.index++
+	return chunk, nil
+}
+
+// InsertIntoUploadReader streams CSV or NDJSON rows from r into an existing table. The input is
+// split on row boundaries into chunks of at most options.MaxChunkBytes, each sent with
+// InsertIntoUpload, and the rows and bytes written are summed over all chunks. For CSV input the
+// first row must be the header. When a chunk fails the returned response still holds the totals
+// of the chunks written so far. Cancelling ctx, or the first failed chunk, aborts the requests in
+// flight. With options.Validate, every row is checked against the table schema before the first
+// chunk is sent, and invalid rows are reported as ValidationErrors with nothing written.
+func (c *duneClient) InsertIntoUploadReader(
+	ctx context.Context, namespace, tableName string, r io.Reader, contentType string, options models.InsertOptions,
+) (*models.UploadsInsertResponse, error) {
+	if options.Validate {
+		validated, cleanup, err := c.validateInput(ctx, namespace, tableName, r, contentType)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		r = validated
+	}
+
+	chunks, err := newChunker(r, contentType, options.MaxChunkBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &models.UploadsInsertResponse{}
+	var mu sync.Mutex
+	send := func(ctx context.Context, chunk *insertChunk) error {
+		resp, err := c.insertIntoUpload(ctx, namespace, tableName, string(chunk.data), contentType)
+		if err != nil {
+			return fmt.Errorf("chunk %d starting at line %d: %w", chunk.index, chunk.firstLine, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		total.Name = resp.Name
+		total.RowsWritten += resp.RowsWritten
+		total.BytesWritten += resp.BytesWritten
+		return nil
+	}
+
+	if options.Concurrency <= 1 {
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+			chunk, err := chunks.next()
+			if err == io.EOF {
+				return total, nil
+			}
+			if err != nil {
+				return total, err
+			}
+			if err := send(ctx, chunk); err != nil {
+				return total, err
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var firstErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	queue := make(chan *insertChunk)
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range queue {
+				if err := send(ctx, chunk); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+produce:
+	for {
+		chunk, err := chunks.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+		select {
+		case queue <- chunk:
+		case <-ctx.Done():
+			break produce
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return total, firstErr
+	}
+	return total, ctx.Err()
+}
@@ -0,0 +1,142 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func readChunks(t *testing.T, input, contentType string, maxBytes int) []*insertChunk {
+	chunks, err := newChunker(strings.NewReader(input), contentType, maxBytes)
+	require.NoError(t, err)
+	var out []*insertChunk
+	for {
+		chunk, err := chunks.next()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, chunk)
+	}
+}
+
+func TestChunkerCSV(t *testing.T) {
+	input := "id,name\n1,a\n2,\"multi\nline\"\n\n3,c"
+
+	chunks := readChunks(t, input, models.ContentTypeCSV, 24)
+
+	require.Len(t, chunks, 3)
+	require.Equal(t, "id,name\n1,a\n", string(chunks[0].data))
+	require.Equal(t, "id,name\n2,\"multi\nline\"\n", string(chunks[1].data))
+	require.Equal(t, "id,name\n3,c\n", string(chunks[2].data))
+	require.Equal(t, []int{2, 3, 6}, []int{chunks[0].firstLine, chunks[1].firstLine, chunks[2].firstLine})
+	require.Equal(t, int64(8), chunks[0].startOffset)
+	require.Equal(t, int64(12), chunks[0].endOffset)
+	require.Equal(t, int64(len(input)-3), chunks[2].startOffset)
+	require.Equal(t, int64(len(input)), chunks[2].endOffset)
+}
+
+func TestChunkerNDJSON(t *testing.T) {
+	input := "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"
+
+	chunks := readChunks(t, input, models.ContentTypeNDJSON, 18)
+
+	require.Len(t, chunks, 2)
+	require.Equal(t, 2, chunks[0].rows)
+	require.Equal(t, "{\"id\":3}\n", string(chunks[1].data))
+}
+
+func TestChunkerErrors(t *testing.T) {
+	_, err := newChunker(strings.NewReader(""), models.ContentTypeCSV, 0)
+	require.ErrorContains(t, err, "missing CSV header")
+
+	_, err = newChunker(strings.NewReader(""), "application/json", 0)
+	require.ErrorContains(t, err, "unsupported content type")
+
+	chunks, err := newChunker(strings.NewReader("id\n12345678901234567890\n"), models.ContentTypeCSV, 10)
+	require.NoError(t, err)
+	_, err = chunks.next()
+	require.ErrorContains(t, err, "line 2: row of 21 bytes does not fit")
+}
+
+func TestInsertIntoUploadReader(t *testing.T) {
+	for _, concurrency := range []int{0, 3} {
+		var mu sync.Mutex
+		var bodies []string
+		var gotPath, gotContentType string
+
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			gotPath = r.URL.Path
+			gotContentType = r.Header.Get("Content-Type")
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(models.UploadsInsertResponse{
+				Name:         "ns.table",
+				RowsWritten:  int64(strings.Count(string(body), "\n") - 1),
+				BytesWritten: int64(len(body)),
+			})
+		})
+
+		input := "id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n"
+		resp, err := client.InsertIntoUploadReader(context.Background(), "ns", "table",
+			strings.NewReader(input), models.ContentTypeCSV, models.InsertOptions{
+				MaxChunkBytes: 16,
+				Concurrency:   concurrency,
+			})
+
+		require.NoError(t, err)
+		require.Equal(t, "/api/v1/uploads/ns/table/insert", gotPath)
+		require.Equal(t, models.ContentTypeCSV, gotContentType)
+		require.Len(t, bodies, 3)
+		require.Equal(t, "ns.table", resp.Name)
+		require.Equal(t, int64(5), resp.RowsWritten)
+	}
+}
+
+func TestInsertIntoUploadReaderStopsOnError(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "bad row"})
+			return
+		}
+		json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 1})
+	})
+
+	resp, err := client.InsertIntoUploadReader(context.Background(), "ns", "table",
+		strings.NewReader("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"), models.ContentTypeNDJSON,
+		models.InsertOptions{MaxChunkBytes: 10})
+
+	require.ErrorContains(t, err, "chunk 1 starting at line 2")
+	require.ErrorContains(t, err, "bad row")
+	require.Equal(t, 2, calls)
+	require.Equal(t, int64(1), resp.RowsWritten)
+}
+
+func TestInsertIntoUploadReaderCancelsRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		cancel()
+		// the insert only returns once the client gives up on the request
+		<-r.Context().Done()
+	})
+
+	_, err := client.InsertIntoUploadReader(ctx, "ns", "table",
+		strings.NewReader("{\"id\":1}\n"), models.ContentTypeNDJSON, models.InsertOptions{})
+
+	require.ErrorIs(t, err, context.Canceled)
+}
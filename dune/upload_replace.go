@@ -0,0 +1,151 @@
+package dune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// countRows returns the number of data rows of CSV or NDJSON input, the CSV header excluded
+func countRows(r io.Reader, contentType string) (int64, error) {
+	rows, err := newRowReader(r, contentType)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for {
+		_, _, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if rows.csv && n > 0 {
+		n--
+	}
+	return n, nil
+}
+
+// loadAndCount inserts r into a table and checks the table received every row of the input
+func (c *duneClient) loadAndCount(
+	ctx context.Context, namespace, tableName string, r io.Reader, contentType string,
+	options models.InsertOptions, expectedRows int64,
+) (*models.UploadsInsertResponse, error) {
+	resp, err := c.InsertIntoUploadReader(ctx, namespace, tableName, r, contentType, options)
+	if err != nil {
+		return resp, fmt.Errorf("failed to load %s.%s: %w", namespace, tableName, err)
+	}
+	if resp.RowsWritten != expectedRows {
+		return resp, fmt.Errorf("%s.%s received %d rows, expected %d",
+			namespace, tableName, resp.RowsWritten, expectedRows)
+	}
+	return resp, nil
+}
+
+// ReplaceUpload replaces the content of an existing table with the CSV or NDJSON rows of r.
+// The rows are first loaded into a private staging table with the schema of the target, and the
+// row count reported by Dune is checked against the input. While staging, the input is copied to
+// a temporary file, and only once the staging table holds every row is the target cleared and
+// loaded from that copy, so a bad input or a failed load leaves the target untouched and the
+// target receives exactly the bytes checked in staging. The API has no way to rename tables, so
+// readers still briefly see an empty or partially loaded target while it is loaded. The staging
+// table is deleted when the replace succeeds, or fails before the target is cleared. When loading
+// the target fails, the staging table is kept as the only complete copy of the rows on Dune and
+// its name is given in the returned error. Cancelling ctx aborts the request in flight, which is
+// then handled as a failure of that request.
+func (c *duneClient) ReplaceUpload(
+	ctx context.Context, namespace, tableName string, r io.ReadSeeker, contentType string,
+	options models.ReplaceOptions,
+) (resp *models.UploadsInsertResponse, err error) {
+	columns, err := c.uploadColumns(ctx, namespace, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the columns of %s.%s: %w", namespace, tableName, err)
+	}
+	insertOptions := options.InsertOptions
+	if insertOptions.Validate {
+		// validate once against the columns of the target, before any table is touched
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := ValidateUpload(r, contentType, columns); err != nil {
+			return nil, err
+		}
+		insertOptions.Validate = false
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	expectedRows, err := countRows(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	spool, err := os.CreateTemp("", "dune-replace-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	staging := options.StagingTableName
+	if staging == "" {
+		staging = fmt.Sprintf("%s_staging_%d", tableName, time.Now().Unix())
+	}
+	created, err := c.createUpload(ctx, models.UploadsCreateRequest{
+		Namespace:   namespace,
+		TableName:   staging,
+		Schema:      columns,
+		Description: fmt.Sprintf("staging table of %s.%s", namespace, tableName),
+		IsPrivate:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging table %s.%s: %w", namespace, staging, err)
+	}
+	if created.AlreadyExisted {
+		// never delete a table this call did not create
+		return nil, fmt.Errorf("staging table %s.%s already exists", namespace, staging)
+	}
+	keepStaging := false
+	defer func() {
+		if keepStaging {
+			return
+		}
+		// the staging table is deleted even when ctx was cancelled
+		if _, deleteErr := c.deleteUpload(context.WithoutCancel(ctx), namespace, staging); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to delete staging table %s.%s: %w", namespace, staging, deleteErr))
+		}
+	}()
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// the insert reads the input to its end, so the spool holds all of it once staging succeeded
+	staged := io.TeeReader(r, spool)
+	if _, err := c.loadAndCount(ctx, namespace, staging, staged, contentType, insertOptions, expectedRows); err != nil {
+		return nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.clearUpload(ctx, namespace, tableName); err != nil {
+		return nil, fmt.Errorf("failed to clear %s.%s: %w", namespace, tableName, err)
+	}
+	// from here on the staging table holds the only complete copy of the rows on Dune
+	resp, err = c.loadAndCount(ctx, namespace, tableName, spool, contentType, insertOptions, expectedRows)
+	if err != nil {
+		keepStaging = true
+		return resp, fmt.Errorf("%w, its new rows are kept in %s.%s", err, namespace, staging)
+	}
+	return resp, nil
+}
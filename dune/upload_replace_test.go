@@ -0,0 +1,188 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceUpload(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		stagingRows   int64
+		failPath      string
+		expectedCalls []string
+		expectedErr   string
+	}{
+		{
+			name:        "replaces the target",
+			stagingRows: 2,
+			expectedCalls: []string{
+				"GET /api/v1/datasets/ns.rates",
+				"POST /api/v1/uploads",
+				"POST /api/v1/uploads/ns/rates_staging/insert",
+				"POST /api/v1/uploads/ns/rates/clear",
+				"POST /api/v1/uploads/ns/rates/insert",
+				"DELETE /api/v1/uploads/ns/rates_staging",
+			},
+		},
+		{
+			name:        "leaves the target untouched when the staging count is off",
+			stagingRows: 1,
+			expectedCalls: []string{
+				"GET /api/v1/datasets/ns.rates",
+				"POST /api/v1/uploads",
+				"POST /api/v1/uploads/ns/rates_staging/insert",
+				"DELETE /api/v1/uploads/ns/rates_staging",
+			},
+			expectedErr: "ns.rates_staging received 1 rows, expected 2",
+		},
+		{
+			name:        "deletes staging when the target cannot be cleared",
+			stagingRows: 2,
+			failPath:    "/api/v1/uploads/ns/rates/clear",
+			expectedCalls: []string{
+				"GET /api/v1/datasets/ns.rates",
+				"POST /api/v1/uploads",
+				"POST /api/v1/uploads/ns/rates_staging/insert",
+				"POST /api/v1/uploads/ns/rates/clear",
+				"DELETE /api/v1/uploads/ns/rates_staging",
+			},
+			expectedErr: "failed to clear ns.rates",
+		},
+		{
+			name:        "keeps staging when the target fails to load",
+			stagingRows: 2,
+			failPath:    "/api/v1/uploads/ns/rates/insert",
+			expectedCalls: []string{
+				"GET /api/v1/datasets/ns.rates",
+				"POST /api/v1/uploads",
+				"POST /api/v1/uploads/ns/rates_staging/insert",
+				"POST /api/v1/uploads/ns/rates/clear",
+				"POST /api/v1/uploads/ns/rates/insert",
+			},
+			expectedErr: "its new rows are kept in ns.rates_staging",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			var created models.UploadsCreateRequest
+			inserts := map[string]string{}
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method+" "+r.URL.Path)
+				body, _ := io.ReadAll(r.Body)
+				if strings.HasSuffix(r.URL.Path, "/insert") {
+					inserts[r.URL.Path] = string(body)
+				}
+				if r.URL.Path == tc.failPath {
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(ErrorResponse{Error: "unavailable"})
+					return
+				}
+				switch r.URL.Path {
+				case "/api/v1/datasets/ns.rates":
+					json.NewEncoder(w).Encode(models.DatasetResponse{
+						FullName: "dune.ns.rates",
+						Type:     "uploaded_table",
+						Columns:  []models.DatasetColumn{{Name: "day", Type: "timestamp"}, {Name: "rate", Type: "double"}},
+					})
+				case "/api/v1/uploads":
+					json.Unmarshal(body, &created)
+					json.NewEncoder(w).Encode(models.UploadsCreateResponse{TableName: created.TableName})
+				case "/api/v1/uploads/ns/rates_staging/insert":
+					json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: tc.stagingRows})
+				case "/api/v1/uploads/ns/rates/insert":
+					json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 2})
+				default:
+					json.NewEncoder(w).Encode(map[string]any{})
+				}
+			})
+
+			input := "day,rate\n2024-01-01,3.5\n2024-01-02,3.6\n"
+			resp, err := client.ReplaceUpload(context.Background(), "ns", "rates",
+				strings.NewReader(input), models.ContentTypeCSV,
+				models.ReplaceOptions{
+					InsertOptions:    models.InsertOptions{Validate: true},
+					StagingTableName: "rates_staging",
+				})
+
+			require.Equal(t, tc.expectedCalls, calls)
+			require.True(t, created.IsPrivate)
+			require.Equal(t, []models.UploadsColumn{{Name: "day", Type: "timestamp"}, {Name: "rate", Type: "double"}},
+				created.Schema)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(2), resp.RowsWritten)
+			// the target is loaded from the copy of the staged input
+			require.Equal(t, map[string]string{
+				"/api/v1/uploads/ns/rates_staging/insert": input,
+				"/api/v1/uploads/ns/rates/insert":         input,
+			}, inserts)
+		})
+	}
+}
+
+func TestReplaceUploadExistingStaging(t *testing.T) {
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/api/v1/uploads" {
+			json.NewEncoder(w).Encode(models.UploadsCreateResponse{AlreadyExisted: true})
+			return
+		}
+		json.NewEncoder(w).Encode(models.DatasetResponse{
+			FullName: "dune.ns.t",
+			Type:     "uploaded_table",
+			Columns:  []models.DatasetColumn{{Name: "id", Type: "integer"}},
+		})
+	})
+
+	_, err := client.ReplaceUpload(context.Background(), "ns", "t", strings.NewReader("{\"id\":1}\n"),
+		models.ContentTypeNDJSON, models.ReplaceOptions{StagingTableName: "mine"})
+
+	require.ErrorContains(t, err, "staging table ns.mine already exists")
+	require.Equal(t, []string{"GET /api/v1/datasets/ns.t", "POST /api/v1/uploads"}, calls)
+}
+
+func TestReplaceUploadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/api/v1/datasets/ns.t":
+			json.NewEncoder(w).Encode(models.DatasetResponse{
+				FullName: "dune.ns.t",
+				Type:     "uploaded_table",
+				Columns:  []models.DatasetColumn{{Name: "id", Type: "integer"}},
+			})
+		case "/api/v1/uploads/ns/t_staging/insert":
+			cancel()
+			<-r.Context().Done()
+		default:
+			json.NewEncoder(w).Encode(map[string]any{})
+		}
+	})
+
+	_, err := client.ReplaceUpload(ctx, "ns", "t", strings.NewReader("{\"id\":1}\n"),
+		models.ContentTypeNDJSON, models.ReplaceOptions{StagingTableName: "t_staging"})
+
+	require.ErrorIs(t, err, context.Canceled)
+	// the staging table is deleted despite the cancellation
+	require.Equal(t, []string{
+		"GET /api/v1/datasets/ns.t",
+		"POST /api/v1/uploads",
+		"POST /api/v1/uploads/ns/t_staging/insert",
+		"DELETE /api/v1/uploads/ns/t_staging",
+	}, calls)
+}
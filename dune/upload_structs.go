@@ -0,0 +1,325 @@
+package dune
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// duneTag is the struct tag naming the column a field maps to. The tag value is the column
+// name, optionally followed by options: `dune:"amount,type=uint256"` overrides the derived
+// type, `dune:"-"` skips the field. Without a tag, the field name is converted to snake_case.
+const duneTag = "dune"
+
+// timestampLayout is the format of timestamps sent to and returned by Dune
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	bigIntType = reflect.TypeOf(big.Int{})
+	bytesType  = reflect.TypeOf([]byte(nil))
+)
+
+// ErrSchemaMismatch is returned by UploadStructs when the columns of an existing table do not match the struct
+var ErrSchemaMismatch = errors.New("table schema does not match")
+
+// structField maps a struct field to a table column
+type structField struct {
+	index  []int
+	column models.UploadsColumn
+}
+
+// structFields derives the columns of a struct type from its exported fields and their dune tags
+func structFields(t reflect.Type) ([]structField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct type, got %s", t)
+	}
+
+	var fields []structField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get(duneTag)
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = toSnakeCase(f.Name)
+		}
+		columnType, nullable := columnTypeOf(f.Type)
+		for _, option := range strings.Split(options, ",") {
+			if typ, ok := strings.CutPrefix(option, "type="); ok {
+				columnType = models.ColumnType(typ)
+			}
+		}
+		fields = append(fields, structField{
+			index: f.Index,
+			column: models.UploadsColumn{
+				Name:     name,
+				Type:     string(columnType),
+				Nullable: nullable,
+			},
+		})
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("struct %s has no exported fields", t)
+	}
+	return fields, nil
+}
+
+// columnTypeOf maps a Go type to a Dune column type. Pointers are nullable columns, types
+// without a natural column type (structs, maps, slices) are stored as JSON in varchar columns.
+func columnTypeOf(t reflect.Type) (models.ColumnType, bool) {
+	nullable := false
+	if t.Kind() == reflect.Pointer {
+		nullable = true
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return models.ColumnTypeTimestamp, nullable
+	case t == bigIntType:
+		return models.ColumnTypeInt256, nullable
+	case t == bytesType:
+		// nil byte slices are stored as null
+		return models.ColumnTypeVarbinary, true
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8:
+		// fixed size byte arrays such as hashes and addresses
+		return models.ColumnTypeVarbinary, nullable
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return models.ColumnTypeBoolean, nullable
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return models.ColumnTypeInteger, nullable
+	case reflect.Uint, reflect.Uint64:
+		return models.ColumnTypeUint256, nullable
+	case reflect.Float32, reflect.Float64:
+		return models.ColumnTypeDouble, nullable
+	case reflect.String:
+		return models.ColumnTypeVarchar, nullable
+	case reflect.Map, reflect.Slice, reflect.Interface:
+		// nil maps, slices and interfaces are stored as null
+		return models.ColumnTypeVarchar, true
+	}
+	return models.ColumnTypeVarchar, nullable
+}
+
+// StructSchema returns the table schema derived from the fields of T
+func StructSchema[T any]() ([]models.UploadsColumn, error) {
+	fields, err := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return structSchema(fields), nil
+}
+
+func structSchema(fields []structField) []models.UploadsColumn {
+	schema := make([]models.UploadsColumn, 0, len(fields))
+	for _, f := range fields {
+		schema = append(schema, f.column)
+	}
+	return schema
+}
+
+// UploadStructs inserts rows into the table namespace.tableName. The table is looked up first: when it
+// exists its columns must match the schema derived from T or ErrSchemaMismatch is returned, otherwise
+// it is created with that schema and the description and privacy of options. Rows are sent as NDJSON,
+// in chunks when they are large.
+func UploadStructs[T any](
+	ctx context.Context, client DuneClient, namespace, tableName string, rows []T, options models.UploadStructsOptions,
+) (*models.UploadsInsertResponse, error) {
+	fields, err := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return &models.UploadsInsertResponse{}, nil
+	}
+
+	schema := structSchema(fields)
+	dataset, err := client.GetDataset(namespace + "." + tableName)
+	switch {
+	case err == nil:
+		columns := make([]models.UploadsColumn, 0, len(dataset.Columns))
+		for _, col := range dataset.Columns {
+			columns = append(columns, models.UploadsColumn{Name: col.Name, Type: col.Type, Nullable: col.Nullable})
+		}
+		if diffs := schemaDiffs(schema, columns); len(diffs) > 0 {
+			return nil, fmt.Errorf("%w: %s.%s and %s: %s", ErrSchemaMismatch, namespace, tableName,
+				reflect.TypeOf((*T)(nil)).Elem(), strings.Join(diffs, "; "))
+		}
+	case isStatus(err, http.StatusNotFound):
+		created, err := client.CreateUpload(models.UploadsCreateRequest{
+			Namespace:   namespace,
+			TableName:   tableName,
+			Schema:      schema,
+			Description: options.Description,
+			IsPrivate:   options.IsPrivate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create table %s.%s: %w", namespace, tableName, err)
+		}
+		if created.AlreadyExisted {
+			// created so recently that it is not listed as a dataset yet
+			return nil, fmt.Errorf("table %s.%s exists but its columns cannot be read yet, retry later",
+				namespace, tableName)
+		}
+	default:
+		return nil, fmt.Errorf("failed to get the columns of %s.%s: %w", namespace, tableName, err)
+	}
+
+	var buf bytes.Buffer
+	for i := range rows {
+		line, err := encodeStructRow(reflect.ValueOf(&rows[i]).Elem(), fields)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return client.InsertIntoUploadReader(
+		ctx, namespace, tableName, &buf, models.ContentTypeNDJSON, models.InsertOptions{},
+	)
+}
+
+// schemaDiffs describes the differences between the schema derived from a struct and the columns of
+// a table. Integer types of any width are considered the same, as are parameterized variants of a type.
+func schemaDiffs(want, have []models.UploadsColumn) []string {
+	haveTypes := make(map[string]models.ColumnType, len(have))
+	for _, col := range have {
+		haveTypes[col.Name] = comparableColumnType(col.Type)
+	}
+	var diffs []string
+	wanted := make(map[string]bool, len(want))
+	for _, col := range want {
+		wanted[col.Name] = true
+		typ, ok := haveTypes[col.Name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("column %s is missing from the table", col.Name))
+		case typ != comparableColumnType(col.Type):
+			diffs = append(diffs, fmt.Sprintf("column %s is %s in the table but %s in the struct",
+				col.Name, typ, comparableColumnType(col.Type)))
+		}
+	}
+	for _, col := range have {
+		// nullable columns missing from the struct are inserted as nulls
+		if !wanted[col.Name] && !col.Nullable {
+			diffs = append(diffs, fmt.Sprintf("column %s is not nullable and missing from the struct", col.Name))
+		}
+	}
+	return diffs
+}
+
+// comparableColumnType returns the base type of a column type, integer types below 256 bits being
+// reported as integer since tables store them as bigint
+func comparableColumnType(s string) models.ColumnType {
+	switch typ := models.BaseColumnType(s); typ {
+	case models.ColumnTypeTinyint, models.ColumnTypeSmallint, models.ColumnTypeBigint:
+		return models.ColumnTypeInteger
+	default:
+		return typ
+	}
+}
+
+// encodeStructRow serializes an addressable struct value as a single NDJSON object
+func encodeStructRow(v reflect.Value, fields []structField) ([]byte, error) {
+	row := make(map[string]any, len(fields))
+	for _, f := range fields {
+		field, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// promoted through a nil embedded pointer
+			row[f.column.Name] = nil
+			continue
+		}
+		value, err := uploadValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", f.column.Name, err)
+		}
+		row[f.column.Name] = value
+	}
+	return json.Marshal(row)
+}
+
+// uploadValue converts a field to the JSON value expected by Dune for its column type
+func uploadValue(v reflect.Value) (any, error) {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface ||
+		v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).UTC().Format(timestampLayout), nil
+	case v.Type() == bigIntType:
+		n := v.Addr().Interface().(*big.Int)
+		return json.Number(n.String()), nil
+	case v.Type() == bytesType:
+		return "0x" + hex.EncodeToString(v.Bytes()), nil
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return "0x" + hex.EncodeToString(b), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		nested, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return string(nested), nil
+	case reflect.Uint, reflect.Uint64:
+		// uint256 columns, sent as a number literal to preserve precision
+		return json.Number(fmt.Sprint(v.Uint())), nil
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return nil, errors.New("unsupported type " + v.Type().String())
+	}
+	return v.Interface(), nil
+}
+
+// toSnakeCase converts a Go identifier to snake_case, keeping acronyms together:
+// TxHash -> tx_hash, USDValue -> usd_value
+func toSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		isUpper := r >= 'A' && r <= 'Z'
+		if isUpper && i > 0 {
+			prevLower := runes[i-1] >= 'a' && runes[i-1] <= 'z' || runes[i-1] >= '0' && runes[i-1] <= '9'
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			prevUpper := runes[i-1] >= 'A' && runes[i-1] <= 'Z'
+			if prevLower || (prevUpper && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if isUpper {
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
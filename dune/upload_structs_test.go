@@ -0,0 +1,176 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+type tradeMeta struct {
+	Source string `json:"source"`
+}
+
+type trade struct {
+	TxHash    []byte
+	Pool      [4]byte
+	BlockTime time.Time `dune:"block_time"`
+	Amount    *big.Int  `dune:"amount,type=uint256"`
+	USDValue  *float64
+	Count     int
+	Success   bool
+	Meta      tradeMeta
+	Labels    []string
+	Ignored   string `dune:"-"`
+	internal  string
+}
+
+func TestStructSchema(t *testing.T) {
+	schema, err := StructSchema[trade]()
+
+	require.NoError(t, err)
+	require.Equal(t, []models.UploadsColumn{
+		{Name: "tx_hash", Type: "varbinary", Nullable: true},
+		{Name: "pool", Type: "varbinary"},
+		{Name: "block_time", Type: "timestamp"},
+		{Name: "amount", Type: "uint256", Nullable: true},
+		{Name: "usd_value", Type: "double", Nullable: true},
+		{Name: "count", Type: "integer"},
+		{Name: "success", Type: "boolean"},
+		{Name: "meta", Type: "varchar"},
+		{Name: "labels", Type: "varchar", Nullable: true},
+	}, schema)
+
+	_, err = StructSchema[int]()
+	require.ErrorContains(t, err, "expected a struct type")
+}
+
+func TestUploadStructs(t *testing.T) {
+	var gotCreate models.UploadsCreateRequest
+	var gotInsert string
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/uploads":
+			json.NewDecoder(r.Body).Decode(&gotCreate)
+			json.NewEncoder(w).Encode(models.UploadsCreateResponse{AlreadyExisted: false})
+		case "/api/v1/uploads/ns/trades/insert":
+			body, _ := io.ReadAll(r.Body)
+			gotInsert = string(body)
+			json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 2})
+		default:
+			// the table does not exist yet
+			http.NotFound(w, r)
+		}
+	})
+
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	usd := 1.5
+	rows := []trade{
+		{
+			TxHash:    []byte{0xab, 0xcd},
+			Pool:      [4]byte{0xde, 0xad, 0xbe, 0xef},
+			BlockTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Amount:    amount,
+			USDValue:  &usd,
+			Count:     3,
+			Success:   true,
+			Meta:      tradeMeta{Source: "api"},
+			Labels:    []string{"a"},
+		},
+		{},
+	}
+
+	resp, err := UploadStructs(context.Background(), client, "ns", "trades", rows,
+		models.UploadStructsOptions{Description: "trades", IsPrivate: true})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.RowsWritten)
+	require.Equal(t, "trades", gotCreate.TableName)
+	require.True(t, gotCreate.IsPrivate)
+	require.Len(t, gotCreate.Schema, 9)
+
+	lines := strings.Split(strings.TrimSpace(gotInsert), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{
+		"tx_hash": "0xabcd",
+		"pool": "0xdeadbeef",
+		"block_time": "2024-01-02 03:04:05.000",
+		"amount": 123456789012345678901234567890,
+		"usd_value": 1.5,
+		"count": 3,
+		"success": true,
+		"meta": "{\"source\":\"api\"}",
+		"labels": "[\"a\"]"
+	}`, lines[0])
+	require.JSONEq(t, `{
+		"tx_hash": null,
+		"pool": "0x00000000",
+		"block_time": "0001-01-01 00:00:00.000",
+		"amount": null,
+		"usd_value": null,
+		"count": 0,
+		"success": false,
+		"meta": "{\"source\":\"\"}",
+		"labels": null
+	}`, lines[1])
+}
+
+func TestUploadStructsExistingTable(t *testing.T) {
+	type rate struct {
+		Date time.Time `dune:"date"`
+		Rate *float64  `dune:"rate"`
+	}
+	var creates, inserts int
+	columns := []models.DatasetColumn{
+		{Name: "date", Type: "timestamp(3) with time zone"},
+		{Name: "rate", Type: "double", Nullable: true},
+		{Name: "note", Type: "varchar", Nullable: true},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/uploads":
+			creates++
+		case "/api/v1/datasets/ns.rates":
+			json.NewEncoder(w).Encode(models.DatasetResponse{FullName: "ns.rates", Type: "uploaded_table", Columns: columns})
+		case "/api/v1/uploads/ns/rates/insert":
+			inserts++
+			json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 1})
+		}
+	})
+	rows := []rate{{Date: time.Now()}}
+
+	// nullable columns missing from the struct are allowed
+	_, err := UploadStructs(context.Background(), client, "ns", "rates", rows, models.UploadStructsOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 0, creates)
+	require.Equal(t, 1, inserts)
+
+	columns[0].Type = "varchar"
+	columns[2].Nullable = false
+	_, err = UploadStructs(context.Background(), client, "ns", "rates", rows, models.UploadStructsOptions{})
+	require.ErrorIs(t, err, ErrSchemaMismatch)
+	require.EqualError(t, err, "table schema does not match: ns.rates and dune.rate: "+
+		"column date is varchar in the table but timestamp in the struct; "+
+		"column note is not nullable and missing from the struct")
+	require.Equal(t, 1, inserts)
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for in, out := range map[string]string{
+		"TxHash":    "tx_hash",
+		"USDValue":  "usd_value",
+		"ID":        "id",
+		"Block2Num": "block2_num",
+		"name":      "name",
+	} {
+		require.Equal(t, out, toSnakeCase(in))
+	}
+}
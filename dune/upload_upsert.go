@@ -0,0 +1,442 @@
+package dune
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// sqlPollInterval is the delay between two status checks of the SQL executions run by the client
+var sqlPollInterval = 2 * time.Second
+
+// runSQLCSV executes sql, waits for it to complete and returns its results as CSV. Results are read
+// as CSV rather than JSON so large integers keep their precision. The execution is cancelled when
+// ctx is done.
+func (c *duneClient) runSQLCSV(ctx context.Context, sql string) (io.Reader, error) {
+	execution, err := c.RunSQL(models.ExecuteSQLRequest{SQL: sql})
+	if err != nil {
+		return nil, err
+	}
+	if err := waitExecution(ctx, c, execution); err != nil {
+		return nil, err
+	}
+	return c.QueryResultsCSV(execution.GetID())
+}
+
+// waitExecution polls the status of an execution until it completes, returning an error if it
+// fails. The execution is cancelled when ctx is done.
+func waitExecution(ctx context.Context, client DuneClient, execution Execution) error {
+	stop := context.AfterFunc(ctx, func() {
+		execution.Cancel()
+	})
+	defer stop()
+
+	for {
+		status, err := client.QueryStatus(execution.GetID())
+		if err != nil {
+			return err
+		}
+		switch status.State {
+		case "QUERY_STATE_COMPLETED":
+			return nil
+		case "QUERY_STATE_FAILED", "QUERY_STATE_CANCELLED", "QUERY_STATE_EXPIRED":
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if status.Error != nil {
+				return fmt.Errorf("execution %s failed: %s", execution.GetID(), status.Error.Message)
+			}
+			return fmt.Errorf("execution %s ended in state %s", execution.GetID(), status.State)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sqlPollInterval):
+		}
+	}
+}
+
+// quoteIdentifier quotes a column or table name for use in DuneSQL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// uploadTableSQL is the name under which an uploaded table is queried
+func uploadTableSQL(namespace, tableName string) string {
+	return "dune." + quoteIdentifier(namespace) + "." + quoteIdentifier(tableName)
+}
+
+// normalizeKeyValue gives equal values of a column type the same text, whether they come from
+// the input or from query results: 1.50 and 1.5, 0xAB and 0xab, or timestamps in any layout.
+func normalizeKeyValue(columnType, value string) string {
+	value = strings.TrimSpace(value)
+	switch typ := models.BaseColumnType(columnType); {
+	case typ == models.ColumnTypeVarbinary || typ == models.ColumnTypeBoolean:
+		return strings.ToLower(value)
+	case typ == models.ColumnTypeTimestamp || typ == models.ColumnTypeDate:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format(time.RFC3339Nano)
+			}
+		}
+	case typ.IsNumeric():
+		if n, ok := new(big.Rat).SetString(value); ok {
+			return n.RatString()
+		}
+	}
+	return value
+}
+
+// upsertKey identifies rows by the normalized values of the key columns
+type upsertKey struct {
+	columns []string
+	types   []string
+}
+
+func (k upsertKey) of(values func(column string) string) string {
+	parts := make([]string, len(k.columns))
+	for i, column := range k.columns {
+		parts[i] = normalizeKeyValue(k.types[i], values(column))
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// upsertRow is a data row of the input
+type upsertRow struct {
+	data []byte
+	key  string
+	// values holds the fields of a CSV row by column name
+	values map[string]string
+}
+
+// readUpsertRows reads the rows of CSV or NDJSON input and computes their key, rejecting rows
+// with a null, empty or missing key column. The raw CSV header row is returned along with its
+// parsed column names.
+func readUpsertRows(r io.Reader, contentType string, key upsertKey) ([]byte, []string, []upsertRow, error) {
+	rows, err := newRowReader(r, contentType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var header []byte
+	var names []string
+	if rows.csv {
+		row, line, err := rows.next()
+		if err == io.EOF {
+			return nil, nil, nil, errors.New("missing CSV header")
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if names, err = csv.NewReader(bytes.NewReader(row)).Read(); err != nil {
+			return nil, nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		for _, column := range key.columns {
+			if !slices.Contains(names, column) {
+				return nil, nil, nil, fmt.Errorf("key column %s is missing from the CSV header", column)
+			}
+		}
+		header = row
+	}
+
+	var out []upsertRow
+	for {
+		row, line, err := rows.next()
+		if err == io.EOF {
+			return header, names, out, nil
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		values := map[string]string{}
+		if rows.csv {
+			reader := csv.NewReader(bytes.NewReader(row))
+			reader.FieldsPerRecord = len(names)
+			record, err := reader.Read()
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			for i, name := range names {
+				values[name] = record[i]
+			}
+		} else {
+			_, decoded, err := decodeNDJSONRow(row)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			for _, column := range key.columns {
+				values[column] = jsonText(decoded[column])
+			}
+		}
+		for _, column := range key.columns {
+			if strings.TrimSpace(values[column]) == "" {
+				return nil, nil, nil, fmt.Errorf("line %d: key column %s is null or missing", line, column)
+			}
+		}
+		out = append(out, upsertRow{
+			data: row,
+			key: key.of(func(column string) string {
+				return values[column]
+			}),
+			values: values,
+		})
+	}
+}
+
+// jsonText returns the text of a decoded JSON value as it would appear in a CSV file
+func jsonText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	nested, _ := json.Marshal(value)
+	return string(nested)
+}
+
+// jsonValue converts a CSV result value to the JSON value of an NDJSON row
+func jsonValue(columnType, text string) any {
+	if text == "" {
+		return nil
+	}
+	switch typ := models.BaseColumnType(columnType); {
+	case typ == models.ColumnTypeBoolean:
+		return strings.EqualFold(text, "true")
+	case typ.IsNumeric():
+		if decimalPattern.MatchString(text) {
+			return json.Number(text)
+		}
+	}
+	return uploadResultValue(columnType, text)
+}
+
+// uploadResultValue converts a value of the CSV results of a query to the text inserted into a
+// column of the given type: timestamps such as 2024-01-01 00:00:00.000 UTC are given in
+// timestampLayout, other values are kept as they are.
+func uploadResultValue(columnType, value string) string {
+	switch models.BaseColumnType(columnType) {
+	case models.ColumnTypeTimestamp, models.ColumnTypeDate:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format(timestampLayout)
+			}
+		}
+	}
+	return value
+}
+
+// UpsertIntoUpload inserts the CSV or NDJSON rows of r into an existing table, deduplicated on
+// options.KeyColumns. The keys already in the table are read with RunSQL, then input rows with
+// an existing key are dropped (UpsertSkip) or replace the existing rows (UpsertReplace). Rows
+// repeating a key of the input are dropped as well: the first one is kept with UpsertSkip, the
+// last one with UpsertReplace. Rows with a null or missing key column are rejected, as they
+// cannot be matched with the rows of the table. The input is held in memory.
+//
+// Both modes cost a SQL execution, billed in credits, reading the whole table: UpsertSkip reads
+// the distinct keys, UpsertReplace every row. UpsertReplace then holds the table in memory and
+// loads it twice through ReplaceUpload, so it refuses tables of more than options.MaxReplaceRows
+// rows. Existing rows are rewritten from their CSV results, which do not tell a null from an empty
+// string.
+func (c *duneClient) UpsertIntoUpload(
+	ctx context.Context, namespace, tableName string, r io.Reader, contentType string,
+	options models.UpsertOptions,
+) (*models.UpsertResponse, error) {
+	if len(options.KeyColumns) == 0 {
+		return nil, errors.New("at least one key column is required")
+	}
+	mode := options.Mode
+	if mode == "" {
+		mode = models.UpsertSkip
+	}
+	if mode != models.UpsertSkip && mode != models.UpsertReplace {
+		return nil, fmt.Errorf("unknown upsert mode %q", mode)
+	}
+
+	columns, err := c.uploadColumns(ctx, namespace, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the columns of %s.%s: %w", namespace, tableName, err)
+	}
+	columnTypes := make(map[string]string, len(columns))
+	for _, column := range columns {
+		columnTypes[column.Name] = column.Type
+	}
+	key := upsertKey{columns: options.KeyColumns}
+	quoted := make([]string, len(options.KeyColumns))
+	for i, name := range options.KeyColumns {
+		typ, ok := columnTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("key column %s is not a column of %s.%s", name, namespace, tableName)
+		}
+		key.types = append(key.types, typ)
+		quoted[i] = quoteIdentifier(name)
+	}
+
+	header, names, rows, err := readUpsertRows(r, contentType, key)
+	if err != nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("SELECT DISTINCT %s FROM %s", strings.Join(quoted, ", "), uploadTableSQL(namespace, tableName))
+	maxRows := options.MaxReplaceRows
+	if maxRows <= 0 {
+		maxRows = models.DefaultMaxReplaceRows
+	}
+	if mode == models.UpsertReplace {
+		// one row more than the limit tells whether the table is above it
+		sql = fmt.Sprintf("SELECT * FROM %s LIMIT %d", uploadTableSQL(namespace, tableName), maxRows+1)
+	}
+	results, err := c.runSQLCSV(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the keys of %s.%s: %w", namespace, tableName, err)
+	}
+	existing, err := csv.NewReader(results).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the keys of %s.%s: %w", namespace, tableName, err)
+	}
+	if mode == models.UpsertReplace && len(existing) > maxRows+1 {
+		return nil, fmt.Errorf("%s.%s holds more than %d rows, too many to be rewritten by %s upserts",
+			namespace, tableName, maxRows, models.UpsertReplace)
+	}
+	var existingHeader []string
+	existingKeys := map[string]bool{}
+	existingRows := make([]string, 0, len(existing))
+	if len(existing) > 0 {
+		existingHeader, existing = existing[0], existing[1:]
+		index := make(map[string]int, len(existingHeader))
+		for i, name := range existingHeader {
+			index[name] = i
+		}
+		for _, record := range existing {
+			k := key.of(func(column string) string {
+				return record[index[column]]
+			})
+			existingKeys[k] = true
+			existingRows = append(existingRows, k)
+		}
+	}
+
+	if mode == models.UpsertSkip {
+		return c.upsertSkip(ctx, namespace, tableName, contentType, options.InsertOptions, header, rows, existingKeys)
+	}
+
+	resp := &models.UpsertResponse{}
+	last := make(map[string]int, len(rows))
+	for i, row := range rows {
+		last[row.key] = i
+	}
+	var buf bytes.Buffer
+	csvOut := csv.NewWriter(&buf)
+	if contentType == models.ContentTypeCSV {
+		if existingHeader == nil {
+			existingHeader = names
+		}
+		csvOut.Write(existingHeader)
+	}
+	for i, record := range existing {
+		if _, replaced := last[existingRows[i]]; replaced {
+			continue
+		}
+		if contentType == models.ContentTypeCSV {
+			for j, name := range existingHeader {
+				record[j] = uploadResultValue(columnTypes[name], record[j])
+			}
+			csvOut.Write(record)
+			continue
+		}
+		object := make(map[string]any, len(record))
+		for j, name := range existingHeader {
+			object[name] = jsonValue(columnTypes[name], record[j])
+		}
+		line, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	for i, row := range rows {
+		switch {
+		case last[row.key] != i:
+			resp.RowsSkipped++
+			continue
+		case existingKeys[row.key]:
+			resp.RowsReplaced++
+		default:
+			resp.RowsInserted++
+		}
+		if contentType != models.ContentTypeCSV {
+			buf.Write(row.data)
+			continue
+		}
+		record := make([]string, len(existingHeader))
+		for j, name := range existingHeader {
+			record[j] = row.values[name]
+		}
+		csvOut.Write(record)
+	}
+	csvOut.Flush()
+	if err := csvOut.Error(); err != nil {
+		return nil, err
+	}
+
+	written, err := c.ReplaceUpload(ctx, namespace, tableName, bytes.NewReader(buf.Bytes()), contentType,
+		models.ReplaceOptions{InsertOptions: options.InsertOptions})
+	if written != nil {
+		resp.UploadsInsertResponse = *written
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// upsertSkip inserts the rows with a key neither in the table nor earlier in the input
+func (c *duneClient) upsertSkip(
+	ctx context.Context, namespace, tableName, contentType string, options models.InsertOptions,
+	header []byte, rows []upsertRow, existingKeys map[string]bool,
+) (*models.UpsertResponse, error) {
+	resp := &models.UpsertResponse{}
+	buf := bytes.NewBuffer(append([]byte{}, header...))
+	for _, row := range rows {
+		if existingKeys[row.key] {
+			resp.RowsSkipped++
+			continue
+		}
+		existingKeys[row.key] = true
+		resp.RowsInserted++
+		buf.Write(row.data)
+	}
+	if resp.RowsInserted == 0 {
+		return resp, nil
+	}
+
+	written, err := c.InsertIntoUploadReader(ctx, namespace, tableName, buf, contentType, options)
+	if written != nil {
+		resp.UploadsInsertResponse = *written
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,167 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+const upsertExecutionID = "01ABCDEFGHIJKLMNOPQRSTUVWX"
+
+// upsertServer serves a table ns.t whose current content is given as CSV results
+type upsertServer struct {
+	results string
+	sql     []string
+	inserts map[string][]string
+}
+
+func (s *upsertServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == "/api/v1/datasets/ns.t":
+		json.NewEncoder(w).Encode(models.DatasetResponse{
+			FullName: "dune.ns.t",
+			Type:     "uploaded_table",
+			Columns: []models.DatasetColumn{
+				{Name: "id", Type: "uint256"},
+				{Name: "day", Type: "timestamp"},
+				{Name: "amount", Type: "double", Nullable: true},
+			},
+		})
+	case r.URL.Path == "/api/v1/sql/execute":
+		var req models.ExecuteSQLRequest
+		json.Unmarshal(body, &req)
+		s.sql = append(s.sql, req.SQL)
+		json.NewEncoder(w).Encode(models.ExecuteResponse{ExecutionID: upsertExecutionID, State: "QUERY_STATE_PENDING"})
+	case r.URL.Path == "/api/v1/execution/"+upsertExecutionID+"/status":
+		ended := time.Now()
+		json.NewEncoder(w).Encode(models.StatusResponse{
+			ExecutionID:      upsertExecutionID,
+			State:            "QUERY_STATE_COMPLETED",
+			ExecutionEndedAt: &ended,
+			ResultMetadata:   &models.ResultMetadata{},
+		})
+	case r.URL.Path == "/api/v1/execution/"+upsertExecutionID+"/results/csv":
+		io.WriteString(w, s.results)
+	case strings.HasSuffix(r.URL.Path, "/insert"):
+		if s.inserts == nil {
+			s.inserts = map[string][]string{}
+		}
+		s.inserts[r.URL.Path] = append(s.inserts[r.URL.Path], string(body))
+		rows := strings.Count(string(body), "\n")
+		if r.Header.Get("Content-Type") == models.ContentTypeCSV {
+			rows--
+		}
+		json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: int64(rows)})
+	case r.URL.Path == "/api/v1/uploads":
+		json.NewEncoder(w).Encode(models.UploadsCreateResponse{})
+	default:
+		json.NewEncoder(w).Encode(map[string]any{})
+	}
+}
+
+func TestUpsertIntoUploadSkip(t *testing.T) {
+	const maxUint256 = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+	server := &upsertServer{
+		results: "id,day\n1,2024-01-01 00:00:00.000 UTC\n" + maxUint256 + ",2024-01-02 00:00:00.000 UTC\n",
+	}
+	client := newTestClient(t, server.handle)
+
+	input := `{"id": 1, "day": "2024-01-01T00:00:00Z", "amount": 1}
+{"id": 1, "day": "2024-01-02", "amount": 2}
+{"id": ` + maxUint256 + `, "day": "2024-01-02"}
+{"id": 1, "day": "2024-01-02 00:00:00", "amount": 3}
+`
+	resp, err := client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(input),
+		models.ContentTypeNDJSON, models.UpsertOptions{KeyColumns: []string{"id", "day"}})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{`SELECT DISTINCT "id", "day" FROM dune."ns"."t"`}, server.sql)
+	require.Equal(t, map[string][]string{
+		"/api/v1/uploads/ns/t/insert": {"{\"id\": 1, \"day\": \"2024-01-02\", \"amount\": 2}\n"},
+	}, server.inserts)
+	require.Equal(t, int64(1), resp.RowsInserted)
+	require.Equal(t, int64(3), resp.RowsSkipped)
+	require.Equal(t, int64(1), resp.RowsWritten)
+}
+
+func TestUpsertIntoUploadSkipNothingNew(t *testing.T) {
+	server := &upsertServer{results: "id\n1\n"}
+	client := newTestClient(t, server.handle)
+
+	resp, err := client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader("id,day\n1,2024-01-01\n"),
+		models.ContentTypeCSV, models.UpsertOptions{KeyColumns: []string{"id"}})
+
+	require.NoError(t, err)
+	require.Nil(t, server.inserts)
+	require.Equal(t, int64(1), resp.RowsSkipped)
+}
+
+func TestUpsertIntoUploadReplace(t *testing.T) {
+	server := &upsertServer{
+		results: "id,day,amount\n1,2024-01-01 00:00:00.000 UTC,1.5\n2,2024-01-02 00:00:00.000 UTC,\n",
+	}
+	client := newTestClient(t, server.handle)
+
+	input := "id,day,amount\n2,2024-01-02,7\n3,2024-01-03,8\n2,2024-01-02,9\n"
+	resp, err := client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(input),
+		models.ContentTypeCSV, models.UpsertOptions{KeyColumns: []string{"id"}, Mode: models.UpsertReplace})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{`SELECT * FROM dune."ns"."t" LIMIT 100001`}, server.sql)
+	// existing timestamps are given in a layout uploads accept
+	expected := "id,day,amount\n1,2024-01-01 00:00:00.000,1.5\n3,2024-01-03,8\n2,2024-01-02,9\n"
+	require.Len(t, server.inserts, 2)
+	for path, bodies := range server.inserts {
+		require.Equal(t, []string{expected}, bodies, path)
+	}
+	require.Equal(t, int64(1), resp.RowsInserted)
+	require.Equal(t, int64(1), resp.RowsReplaced)
+	require.Equal(t, int64(1), resp.RowsSkipped)
+}
+
+func TestUpsertIntoUploadReplaceTooLarge(t *testing.T) {
+	server := &upsertServer{results: "id\n1\n2\n3\n"}
+	client := newTestClient(t, server.handle)
+
+	options := models.UpsertOptions{KeyColumns: []string{"id"}, Mode: models.UpsertReplace, MaxReplaceRows: 2}
+	_, err := client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader("id\n4\n"),
+		models.ContentTypeCSV, options)
+
+	require.EqualError(t, err, "ns.t holds more than 2 rows, too many to be rewritten by replace upserts")
+	require.Equal(t, []string{`SELECT * FROM dune."ns"."t" LIMIT 3`}, server.sql)
+	require.Nil(t, server.inserts)
+}
+
+func TestUpsertIntoUploadErrors(t *testing.T) {
+	client := newTestClient(t, (&upsertServer{}).handle)
+
+	_, err := client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(""),
+		models.ContentTypeCSV, models.UpsertOptions{})
+	require.ErrorContains(t, err, "at least one key column is required")
+
+	_, err = client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(""),
+		models.ContentTypeCSV, models.UpsertOptions{KeyColumns: []string{"nope"}})
+	require.ErrorContains(t, err, "key column nope is not a column of ns.t")
+
+	_, err = client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader("day\n2024-01-01\n"),
+		models.ContentTypeCSV, models.UpsertOptions{KeyColumns: []string{"id"}})
+	require.ErrorContains(t, err, "key column id is missing from the CSV header")
+
+	csvInput := "id,day\n1,2024-01-01\n,2024-01-02\n"
+	_, err = client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(csvInput),
+		models.ContentTypeCSV, models.UpsertOptions{KeyColumns: []string{"id"}})
+	require.ErrorContains(t, err, "line 3: key column id is null or missing")
+
+	ndjsonInput := "{\"id\": null}\n{\"day\": \"2024-01-01\"}\n"
+	_, err = client.UpsertIntoUpload(context.Background(), "ns", "t", strings.NewReader(ndjsonInput),
+		models.ContentTypeNDJSON, models.UpsertOptions{KeyColumns: []string{"id"}})
+	require.ErrorContains(t, err, "line 1: key column id is null or missing")
+}
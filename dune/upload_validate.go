@@ -0,0 +1,350 @@
+package dune
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// maxValidationErrors caps the number of errors collected before validation gives up
+const maxValidationErrors = 100
+
+// ValidationError reports a value of the input that does not match the table schema.
+// Column is empty for errors affecting a whole row.
+type ValidationError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %s: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors is returned when rows do not match the table schema
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, min(len(e), 5))
+	for i := 0; i < len(e) && i < 5; i++ {
+		msgs = append(msgs, e[i].Error())
+	}
+	if len(e) > 5 {
+		msgs = append(msgs, fmt.Sprintf("and %d more", len(e)-5))
+	}
+	return fmt.Sprintf("%d invalid values: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// rowValidator checks CSV or NDJSON rows against the columns of a table
+type rowValidator struct {
+	csv     bool
+	ordered []models.UploadsColumn
+	columns map[string]models.UploadsColumn
+	// csvColumns holds the table columns in the order of the CSV header
+	csvColumns []models.UploadsColumn
+}
+
+func newRowValidator(columns []models.UploadsColumn, contentType string) *rowValidator {
+	v := &rowValidator{
+		csv:     contentType == models.ContentTypeCSV,
+		ordered: columns,
+		columns: make(map[string]models.UploadsColumn, len(columns)),
+	}
+	for _, c := range columns {
+		v.columns[c.Name] = c
+	}
+	return v
+}
+
+// header checks the CSV header: every field must be a column and every required column must be present
+func (v *rowValidator) header(row []byte, line int) []ValidationError {
+	record, err := csv.NewReader(bytes.NewReader(row)).Read()
+	if err != nil {
+		return []ValidationError{{Line: line, Message: err.Error()}}
+	}
+
+	var errs []ValidationError
+	seen := map[string]bool{}
+	v.csvColumns = make([]models.UploadsColumn, 0, len(record))
+	for _, name := range record {
+		name = strings.TrimSpace(name)
+		column, ok := v.columns[name]
+		if !ok {
+			errs = append(errs, ValidationError{Line: line, Column: name, Message: "unknown column"})
+		}
+		seen[name] = true
+		v.csvColumns = append(v.csvColumns, column)
+	}
+	for _, column := range v.ordered {
+		if !seen[column.Name] && !column.Nullable {
+			errs = append(errs, ValidationError{Line: line, Column: column.Name, Message: "missing non nullable column"})
+		}
+	}
+	return errs
+}
+
+// row checks a single data row
+func (v *rowValidator) row(row []byte, line int) []ValidationError {
+	if v.csv {
+		return v.csvRow(row, line)
+	}
+	return v.ndjsonRow(row, line)
+}
+
+func (v *rowValidator) csvRow(row []byte, line int) []ValidationError {
+	reader := csv.NewReader(bytes.NewReader(row))
+	reader.FieldsPerRecord = -1
+	record, err := reader.Read()
+	if err != nil {
+		return []ValidationError{{Line: line, Message: err.Error()}}
+	}
+	if len(record) != len(v.csvColumns) {
+		return []ValidationError{{
+			Line:    line,
+			Message: fmt.Sprintf("expected %d fields, got %d", len(v.csvColumns), len(record)),
+		}}
+	}
+
+	var errs []ValidationError
+	for i, value := range record {
+		column := v.csvColumns[i]
+		if column.Name == "" {
+			// unknown column, already reported on the header
+			continue
+		}
+		if value == "" {
+			if !column.Nullable {
+				errs = append(errs, ValidationError{Line: line, Column: column.Name, Message: "null value in non nullable column"})
+			}
+			continue
+		}
+		if msg := checkValue(column.Type, value); msg != "" {
+			errs = append(errs, ValidationError{Line: line, Column: column.Name, Value: value, Message: msg})
+		}
+	}
+	return errs
+}
+
+func (v *rowValidator) ndjsonRow(row []byte, line int) []ValidationError {
+	keys, values, err := decodeNDJSONRow(row)
+	if err != nil {
+		return []ValidationError{{Line: line, Message: err.Error()}}
+	}
+
+	var errs []ValidationError
+	for _, name := range keys {
+		column, ok := v.columns[name]
+		if !ok {
+			errs = append(errs, ValidationError{Line: line, Column: name, Message: "unknown column"})
+			continue
+		}
+		var text string
+		switch value := values[name].(type) {
+		case nil:
+			if !column.Nullable {
+				errs = append(errs, ValidationError{Line: line, Column: name, Message: "null value in non nullable column"})
+			}
+			continue
+		case string:
+			text = value
+		case json.Number:
+			text = string(value)
+		case bool:
+			text = strconv.FormatBool(value)
+		default:
+			// objects and arrays can only be stored as text
+			if models.BaseColumnType(column.Type) != models.ColumnTypeVarchar {
+				errs = append(errs, ValidationError{Line: line, Column: name, Message: "expected " + column.Type})
+			}
+			continue
+		}
+		if msg := checkValue(column.Type, text); msg != "" {
+			errs = append(errs, ValidationError{Line: line, Column: name, Value: text, Message: msg})
+		}
+	}
+	for _, column := range v.ordered {
+		if _, ok := values[column.Name]; !ok && !column.Nullable {
+			errs = append(errs, ValidationError{Line: line, Column: column.Name, Message: "missing non nullable column"})
+		}
+	}
+	return errs
+}
+
+// checkValue returns why value cannot be stored in a column of the given type, or "" if it can
+func checkValue(columnType, value string) string {
+	value = strings.TrimSpace(value)
+	switch typ := models.BaseColumnType(columnType); {
+	case typ.IsInteger():
+		if !integerPattern.MatchString(value) {
+			return "expected an integer"
+		}
+		switch typ {
+		case models.ColumnTypeUint256:
+			if strings.HasPrefix(value, "-") {
+				return "expected an unsigned integer"
+			}
+		case models.ColumnTypeInt256:
+		default:
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return "integer out of range"
+			}
+		}
+	case typ.IsNumeric():
+		if !decimalPattern.MatchString(value) {
+			return "expected a number"
+		}
+	case typ == models.ColumnTypeBoolean:
+		switch strings.ToLower(value) {
+		case "true", "false":
+		default:
+			return "expected true or false"
+		}
+	case typ == models.ColumnTypeTimestamp || typ == models.ColumnTypeDate:
+		for _, layout := range timestampLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return ""
+			}
+		}
+		return "expected a timestamp"
+	case typ == models.ColumnTypeVarbinary:
+		if !hexPattern.MatchString(value) {
+			return "expected 0x prefixed hex"
+		}
+	}
+	return ""
+}
+
+// ValidateUpload checks every CSV or NDJSON row of r against the table columns and returns
+// ValidationErrors listing the offending rows and columns, with their line numbers in r.
+// For CSV input the first row must be the header.
+func ValidateUpload(r io.Reader, contentType string, columns []models.UploadsColumn) error {
+	rows, err := newRowReader(r, contentType)
+	if err != nil {
+		return err
+	}
+	v := newRowValidator(columns, contentType)
+
+	var errs ValidationErrors
+	first := true
+	for len(errs) < maxValidationErrors {
+		row, line, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if first && v.csv {
+			errs = append(errs, v.header(row, line)...)
+		} else {
+			errs = append(errs, v.row(row, line)...)
+		}
+		first = false
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateInsert fetches the columns of namespace.tableName and validates r against them with ValidateUpload
+func (c *duneClient) ValidateInsert(namespace, tableName string, r io.Reader, contentType string) error {
+	columns, err := c.uploadColumns(context.Background(), namespace, tableName)
+	if err != nil {
+		return err
+	}
+	return ValidateUpload(r, contentType, columns)
+}
+
+// validateInput checks every row of r against the columns of namespace.tableName and returns a reader
+// replaying r from where it was. Seekable input is validated in place and rewound, other input is copied
+// to a temporary file while it is validated, which cleanup removes.
+func (c *duneClient) validateInput(
+	ctx context.Context, namespace, tableName string, r io.Reader, contentType string,
+) (io.Reader, func(), error) {
+	columns, err := c.uploadColumns(ctx, namespace, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the columns of %s.%s: %w", namespace, tableName, err)
+	}
+
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ValidateUpload(seeker, contentType, columns); err != nil {
+			return nil, nil, err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		return seeker, func() {}, nil
+	}
+
+	spool, err := os.CreateTemp("", "dune-insert-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	if err := ValidateUpload(io.TeeReader(r, spool), contentType, columns); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return spool, cleanup, nil
+}
+
+// uploadColumns returns the columns of an uploaded table. Datasets are looked up first, then
+// the uploads list is searched for tables not yet visible as datasets. Only a missing dataset
+// falls back to the uploads list, other errors are returned.
+func (c *duneClient) uploadColumns(ctx context.Context, namespace, tableName string) ([]models.UploadsColumn, error) {
+	fullName := namespace + "." + tableName
+	dataset, err := c.getDataset(ctx, fullName)
+	if err == nil {
+		columns := make([]models.UploadsColumn, 0, len(dataset.Columns))
+		for _, col := range dataset.Columns {
+			columns = append(columns, models.UploadsColumn{
+				Name:        col.Name,
+				Type:        col.Type,
+				Nullable:    col.Nullable,
+				Description: col.Description,
+			})
+		}
+		return columns, nil
+	}
+	if !isStatus(err, http.StatusNotFound) {
+		return nil, err
+	}
+
+	tables := c.AllUploads(ctx, models.PaginationOptions{})
+	for tables.Next() {
+		table := tables.Value()
+		if table.FullName == fullName || strings.HasSuffix(table.FullName, "."+fullName) {
+			return table.Columns, nil
+		}
+	}
+	if err := tables.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("table %s not found", fullName)
+}
@@ -0,0 +1,140 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+var validateColumns = []models.UploadsColumn{
+	{Name: "id", Type: "integer"},
+	{Name: "price", Type: "decimal(38,2)", Nullable: true},
+	{Name: "active", Type: "boolean", Nullable: true},
+	{Name: "ts", Type: "timestamp", Nullable: true},
+	{Name: "hash", Type: "varbinary", Nullable: true},
+	{Name: "label", Type: "varchar", Nullable: true},
+}
+
+func TestValidateUploadCSV(t *testing.T) {
+	input := "id,price,active,ts,hash\n" +
+		"1,1.5,true,2024-01-01 00:00:00,0xab\n" +
+		"x,1.5,yes,2024-01-01,0xab\n" +
+		",abc,false,not a date,abcd\n" +
+		"3,1\n"
+
+	err := ValidateUpload(strings.NewReader(input), models.ContentTypeCSV, validateColumns)
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	require.Equal(t, ValidationErrors{
+		{Line: 3, Column: "id", Value: "x", Message: "expected an integer"},
+		{Line: 3, Column: "active", Value: "yes", Message: "expected true or false"},
+		{Line: 4, Column: "id", Message: "null value in non nullable column"},
+		{Line: 4, Column: "price", Value: "abc", Message: "expected a number"},
+		{Line: 4, Column: "ts", Value: "not a date", Message: "expected a timestamp"},
+		{Line: 4, Column: "hash", Value: "abcd", Message: "expected 0x prefixed hex"},
+		{Line: 5, Message: "expected 5 fields, got 2"},
+	}, errs)
+	require.Contains(t, err.Error(), "7 invalid values: line 3, column id: expected an integer;")
+}
+
+func TestValidateUploadCSVHeader(t *testing.T) {
+	err := ValidateUpload(strings.NewReader("price,unknown\n"), models.ContentTypeCSV, validateColumns)
+
+	require.Equal(t, ValidationErrors{
+		{Line: 1, Column: "unknown", Message: "unknown column"},
+		{Line: 1, Column: "id", Message: "missing non nullable column"},
+	}, err)
+}
+
+func TestValidateUploadNDJSON(t *testing.T) {
+	input := `{"id": 1, "price": 2.5, "active": true, "label": {"nested": 1}}
+{"id": 1.5, "active": "maybe", "other": 1}
+
+{"price": null, "hash": "0x1"}
+{"id": 2, "ts": "2024-01-01T00:00:00Z"}
+`
+
+	err := ValidateUpload(strings.NewReader(input), models.ContentTypeNDJSON, validateColumns)
+
+	require.Equal(t, ValidationErrors{
+		{Line: 2, Column: "id", Value: "1.5", Message: "expected an integer"},
+		{Line: 2, Column: "active", Value: "maybe", Message: "expected true or false"},
+		{Line: 2, Column: "other", Message: "unknown column"},
+		{Line: 4, Column: "hash", Value: "0x1", Message: "expected 0x prefixed hex"},
+		{Line: 4, Column: "id", Message: "missing non nullable column"},
+	}, err)
+
+	require.NoError(t, ValidateUpload(strings.NewReader(`{"id": 1}`), models.ContentTypeNDJSON, validateColumns))
+}
+
+func TestInsertIntoUploadReaderValidate(t *testing.T) {
+	var inserts int
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/datasets/ns.table":
+			json.NewEncoder(w).Encode(models.DatasetResponse{
+				FullName: "dune.ns.table",
+				Type:     "uploaded_table",
+				Columns: []models.DatasetColumn{
+					{Name: "id", Type: "integer"},
+				},
+			})
+		case "/api/v1/uploads/ns/table/insert":
+			inserts++
+			json.NewEncoder(w).Encode(models.UploadsInsertResponse{RowsWritten: 1})
+		}
+	})
+
+	_, err := client.InsertIntoUploadReader(context.Background(), "ns", "table",
+		strings.NewReader("id\n1\n2\nthree\n"), models.ContentTypeCSV,
+		models.InsertOptions{MaxChunkBytes: 9, Validate: true})
+
+	require.Equal(t, ValidationErrors{{Line: 4, Column: "id", Value: "three", Message: "expected an integer"}}, err)
+	// the whole input is validated before the first chunk is sent
+	require.Equal(t, 0, inserts)
+
+	// input that cannot be rewound is replayed from a copy once validated
+	resp, err := client.InsertIntoUploadReader(context.Background(), "ns", "table",
+		io.MultiReader(strings.NewReader("id\n1\n"), strings.NewReader("2\n")), models.ContentTypeCSV,
+		models.InsertOptions{MaxChunkBytes: 5, Validate: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.RowsWritten)
+	require.Equal(t, 2, inserts)
+}
+
+func TestValidateInsertColumns(t *testing.T) {
+	var datasetStatus int
+	var listed bool
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/datasets/ns.table":
+			http.Error(w, `{"error": "failed"}`, datasetStatus)
+		case "/api/v1/uploads":
+			listed = true
+			json.NewEncoder(w).Encode(models.UploadsListResponse{Tables: []models.UploadsListElement{
+				{FullName: "dune.ns.table", Columns: []models.UploadsColumn{{Name: "id", Type: "integer"}}},
+			}})
+		}
+	})
+
+	// a table not yet visible as a dataset is found in the uploads list
+	datasetStatus = http.StatusNotFound
+	err := client.ValidateInsert("ns", "table", strings.NewReader("id\nthree\n"), models.ContentTypeCSV)
+	require.Equal(t, ValidationErrors{{Line: 2, Column: "id", Value: "three", Message: "expected an integer"}}, err)
+	require.True(t, listed)
+
+	// other errors are returned without listing the uploads
+	listed = false
+	datasetStatus = http.StatusInternalServerError
+	err = client.ValidateInsert("ns", "table", strings.NewReader("id\n1\n"), models.ContentTypeCSV)
+	require.True(t, isStatus(err, http.StatusInternalServerError))
+	require.False(t, listed)
+}
@@ -0,0 +1,204 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/internal/fileutil"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// maxUsageSnapshots is the number of snapshots kept by a UsageHistory, the oldest are dropped first
+const maxUsageSnapshots = 5000
+
+// trendWindow is how far back from the latest snapshot burn rates and storage growth are measured
+const trendWindow = 7 * 24 * time.Hour
+
+// minTrendSpan is the shortest time between snapshots a trend is measured over, so snapshots taken
+// moments apart do not give wild rates
+const minTrendSpan = time.Hour
+
+// ErrNoUsageSnapshot is returned when forecasting from a history without snapshots
+var ErrNoUsageSnapshot = errors.New("no usage snapshot")
+
+// UsageHistory is a series of usage snapshots kept in a file, from which usage trends are forecast
+type UsageHistory struct {
+	path      string
+	Snapshots []models.UsageSnapshot `json:"snapshots"`
+	// OnRecordError, if set, is called by Collect with the errors of the snapshots it could not take
+	OnRecordError func(error) `json:"-"`
+}
+
+// OpenUsageHistory reads the history saved at path, empty if the file does not exist yet
+func OpenUsageHistory(path string) (*UsageHistory, error) {
+	h := &UsageHistory{path: path}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, h); err != nil {
+		return nil, fmt.Errorf("failed to parse usage history %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// save writes the history to its path
+func (h *UsageHistory) save() error {
+	content, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(h.path, content, 0o644)
+}
+
+// Record takes a snapshot of the usage with GetUsage, adds it to the history and saves it
+func (h *UsageHistory) Record(client DuneClient) (models.UsageSnapshot, error) {
+	snapshot, err := takeUsageSnapshot(client)
+	if err != nil {
+		return models.UsageSnapshot{}, err
+	}
+	if err := h.add(snapshot); err != nil {
+		return models.UsageSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// takeUsageSnapshot fetches the usage with GetUsage
+func takeUsageSnapshot(client DuneClient) (models.UsageSnapshot, error) {
+	usage, err := client.GetUsage()
+	if err != nil {
+		return models.UsageSnapshot{}, err
+	}
+	now := time.Now().UTC()
+	snapshot := models.UsageSnapshot{TakenAt: now, BytesUsed: usage.BytesUsed, BytesAllowed: usage.BytesAllowed}
+	if period, ok := CurrentBillingPeriod(usage, now); ok {
+		snapshot.Period = period
+	}
+	return snapshot, nil
+}
+
+// add adds a snapshot to the history and saves it
+func (h *UsageHistory) add(snapshot models.UsageSnapshot) error {
+	h.Snapshots = append(h.Snapshots, snapshot)
+	slices.SortStableFunc(h.Snapshots, func(a, b models.UsageSnapshot) int {
+		return a.TakenAt.Compare(b.TakenAt)
+	})
+	if len(h.Snapshots) > maxUsageSnapshots {
+		h.Snapshots = h.Snapshots[len(h.Snapshots)-maxUsageSnapshots:]
+	}
+	if err := h.save(); err != nil {
+		return fmt.Errorf("failed to save usage history: %w", err)
+	}
+	return nil
+}
+
+// Collect records a snapshot every interval until ctx is done, returning ctx.Err() then. A snapshot
+// that cannot be taken is passed to OnRecordError and taken again at the next tick, while failing to
+// save the history stops the collection with that error.
+func (h *UsageHistory) Collect(ctx context.Context, client DuneClient, interval time.Duration) error {
+	for {
+		snapshot, err := takeUsageSnapshot(client)
+		if err != nil {
+			if h.OnRecordError != nil {
+				h.OnRecordError(err)
+			}
+		} else if err := h.add(snapshot); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Forecast projects the usage from the latest snapshot of the history to the end of its billing period,
+// now being recorded as the time of the forecast. The burn rate is measured between the snapshots of
+// the period over the last week, or averaged since the start of the period until snapshots span an hour.
+// Storage growth is measured the same way over all snapshots, and is unknown until then.
+func (h *UsageHistory) Forecast(now time.Time) (*models.UsageForecast, error) {
+	if len(h.Snapshots) == 0 {
+		return nil, ErrNoUsageSnapshot
+	}
+	latest := h.Snapshots[len(h.Snapshots)-1]
+	forecast := &models.UsageForecast{
+		GeneratedAt:     now,
+		TakenAt:         latest.TakenAt,
+		Period:          latest.Period,
+		CreditsUsed:     latest.Period.CreditsUsed,
+		CreditsIncluded: latest.Period.CreditsIncluded,
+		BytesUsed:       latest.BytesUsed,
+		BytesAllowed:    latest.BytesAllowed,
+	}
+
+	periodStart, periodEnd, okPeriod := billingPeriodBounds(latest.Period)
+
+	var period []models.UsageSnapshot
+	for _, s := range h.Snapshots {
+		if s.Period.StartDate == latest.Period.StartDate && s.Period.EndDate == latest.Period.EndDate {
+			period = append(period, s)
+		}
+	}
+	if first, ok := trendStart(period); ok {
+		forecast.BurnRateSource = models.BurnRateSnapshots
+		credits := latest.Period.CreditsUsed - first.Period.CreditsUsed
+		forecast.CreditsPerDay = credits / days(latest.TakenAt.Sub(first.TakenAt))
+	} else if okPeriod && latest.TakenAt.After(periodStart) {
+		forecast.BurnRateSource = models.BurnRatePeriodAverage
+		forecast.CreditsPerDay = latest.Period.CreditsUsed / days(latest.TakenAt.Sub(periodStart))
+	}
+	forecast.CreditsPerDay = max(forecast.CreditsPerDay, 0)
+
+	forecast.ProjectedCredits = forecast.CreditsUsed
+	if okPeriod && periodEnd.After(latest.TakenAt) {
+		forecast.ProjectedCredits += forecast.CreditsPerDay * days(periodEnd.Sub(latest.TakenAt))
+	}
+	if forecast.CreditsIncluded > 0 {
+		included := float64(forecast.CreditsIncluded)
+		forecast.ProjectedPercent = forecast.ProjectedCredits / included * 100
+		forecast.ProjectedOverage = max(forecast.ProjectedCredits-included, 0)
+	}
+
+	if first, ok := trendStart(h.Snapshots); ok {
+		bytesPerDay := float64(latest.BytesUsed-first.BytesUsed) / days(latest.TakenAt.Sub(first.TakenAt))
+		forecast.BytesPerDay = &bytesPerDay
+		if latest.BytesAllowed > latest.BytesUsed && bytesPerDay > 0 {
+			left := float64(latest.BytesAllowed-latest.BytesUsed) / bytesPerDay
+			exhaustedAt := latest.TakenAt.Add(time.Duration(left * float64(24*time.Hour)))
+			forecast.StorageExhaustedAt = &exhaustedAt
+		}
+	}
+	if latest.BytesAllowed > 0 && latest.BytesUsed >= latest.BytesAllowed {
+		exhaustedAt := latest.TakenAt
+		forecast.StorageExhaustedAt = &exhaustedAt
+	}
+	return forecast, nil
+}
+
+// trendStart returns the oldest snapshot of the trend window ending at the latest of snapshots, or
+// false if it was taken less than minTrendSpan before the latest one
+func trendStart(snapshots []models.UsageSnapshot) (models.UsageSnapshot, bool) {
+	if len(snapshots) < 2 {
+		return models.UsageSnapshot{}, false
+	}
+	latest := snapshots[len(snapshots)-1]
+	for _, s := range snapshots {
+		if latest.TakenAt.Sub(s.TakenAt) <= trendWindow {
+			return s, latest.TakenAt.Sub(s.TakenAt) >= minTrendSpan
+		}
+	}
+	return models.UsageSnapshot{}, false
+}
+
+func days(d time.Duration) float64 {
+	return d.Hours() / 24
+}
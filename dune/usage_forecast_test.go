@@ -0,0 +1,132 @@
+package dune
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageForecast(t *testing.T) {
+	may := models.BillingPeriod{StartDate: "2024-05-01", EndDate: "2024-05-30", CreditsIncluded: 3000}
+	snapshot := func(day int, credits float64, bytesUsed int64) models.UsageSnapshot {
+		period := may
+		period.CreditsUsed = credits
+		return models.UsageSnapshot{
+			TakenAt:      time.Date(2024, 5, day, 0, 0, 0, 0, time.UTC),
+			Period:       period,
+			BytesUsed:    bytesUsed,
+			BytesAllowed: 2_000_000_000,
+		}
+	}
+	now := time.Date(2024, 5, 11, 1, 0, 0, 0, time.UTC)
+
+	_, err := (&UsageHistory{}).Forecast(now)
+	require.ErrorIs(t, err, ErrNoUsageSnapshot)
+
+	// a single snapshot: the average since the start of the period, no storage trend
+	history := &UsageHistory{Snapshots: []models.UsageSnapshot{snapshot(11, 1000, 1_000_000_000)}}
+	forecast, err := history.Forecast(now)
+	require.NoError(t, err)
+	require.Equal(t, models.BurnRatePeriodAverage, forecast.BurnRateSource)
+	require.InDelta(t, 100, forecast.CreditsPerDay, 1e-9)
+	require.InDelta(t, 3000, forecast.ProjectedCredits, 1e-9)
+	require.InDelta(t, 100, forecast.ProjectedPercent, 1e-9)
+	require.Zero(t, forecast.ProjectedOverage)
+	require.Nil(t, forecast.BytesPerDay)
+	require.Nil(t, forecast.StorageExhaustedAt)
+
+	// the trend is measured over the last week of snapshots: the first one is ignored
+	history.Snapshots = []models.UsageSnapshot{
+		snapshot(1, 0, 0),
+		snapshot(10, 1000, 1_000_000_000),
+		snapshot(11, 1100, 1_100_000_000),
+	}
+	forecast, err = history.Forecast(now)
+	require.NoError(t, err)
+	require.Equal(t, now, forecast.GeneratedAt)
+	require.Equal(t, models.BurnRateSnapshots, forecast.BurnRateSource)
+	require.InDelta(t, 100, forecast.CreditsPerDay, 1e-9)
+	require.InDelta(t, 3100, forecast.ProjectedCredits, 1e-9)
+	require.InDelta(t, 100, forecast.ProjectedOverage, 1e-9)
+	require.InDelta(t, 100_000_000, *forecast.BytesPerDay, 1e-6)
+	require.Equal(t, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), *forecast.StorageExhaustedAt)
+
+	// snapshots of a previous period are not used for the burn rate
+	previous := snapshot(10, 2900, 1_000_000_000)
+	previous.Period.StartDate, previous.Period.EndDate = "2024-04-01", "2024-04-30"
+	history.Snapshots = []models.UsageSnapshot{previous, snapshot(11, 1000, 2_000_000_000)}
+	forecast, err = history.Forecast(now)
+	require.NoError(t, err)
+	require.Equal(t, models.BurnRatePeriodAverage, forecast.BurnRateSource)
+	require.Equal(t, history.Snapshots[1].TakenAt, *forecast.StorageExhaustedAt)
+}
+
+func TestUsageHistoryRecord(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/usage", r.URL.Path)
+		json.NewEncoder(w).Encode(models.UsageResponse{
+			BytesUsed:    10,
+			BytesAllowed: 100,
+			BillingPeriods: []models.BillingPeriod{
+				{StartDate: today, EndDate: today, CreditsUsed: 5, CreditsIncluded: 50},
+			},
+		})
+	})
+
+	path := filepath.Join(t.TempDir(), "usage.history.json")
+	history, err := OpenUsageHistory(path)
+	require.NoError(t, err)
+	require.Empty(t, history.Snapshots)
+	snapshot, err := history.Record(client)
+	require.NoError(t, err)
+	require.Equal(t, 5.0, snapshot.Period.CreditsUsed)
+	require.Equal(t, int64(10), snapshot.BytesUsed)
+
+	history, err = OpenUsageHistory(path)
+	require.NoError(t, err)
+	require.Len(t, history.Snapshots, 1)
+	require.True(t, snapshot.TakenAt.Equal(history.Snapshots[0].TakenAt))
+	require.Equal(t, snapshot.Period, history.Snapshots[0].Period)
+}
+
+func TestUsageHistoryCollect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "internal error"})
+			return
+		}
+		cancel()
+		json.NewEncoder(w).Encode(models.UsageResponse{BytesUsed: 10})
+	})
+
+	history, err := OpenUsageHistory(filepath.Join(t.TempDir(), "usage.history.json"))
+	require.NoError(t, err)
+	var recordErrors []error
+	history.OnRecordError = func(err error) {
+		recordErrors = append(recordErrors, err)
+	}
+
+	// the failed snapshot is retried at the next tick
+	err = history.Collect(ctx, client, time.Millisecond)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, recordErrors, 1)
+	require.Len(t, history.Snapshots, 1)
+
+	// failing to save stops the collection
+	history, err = OpenUsageHistory(filepath.Join(t.TempDir(), "missing", "usage.history.json"))
+	require.NoError(t, err)
+	err = history.Collect(context.Background(), client, time.Millisecond)
+	require.ErrorContains(t, err, "failed to save usage history")
+}
@@ -0,0 +1,417 @@
+package dune
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+// UsageRecorder receives the usage of the executions launched by a client created with WithUsageRecorder
+type UsageRecorder interface {
+	RecordExecution(usage models.ExecutionUsage) error
+}
+
+// usageTracker keeps the executions launched by a client until they are recorded, with the last
+// state seen of each
+type usageTracker struct {
+	recorder UsageRecorder
+
+	mu      sync.Mutex
+	pending map[string]models.ExecutionUsage
+	// pipelines maps the pipeline executions launched to their performance
+	pipelines map[string]string
+	// recorded are the executions already recorded, which are not tracked again
+	recorded map[string]bool
+	// errs are the recording errors not returned by FlushUsage yet
+	errs []error
+}
+
+// WithUsageRecorder returns a client sharing the configuration of c that records the usage of the
+// executions it launches with QueryExecute, SQLExecute and QueryPipelineExecute, and the methods built
+// on them such as RunQuery. An execution is recorded once, when its status or results read through the
+// returned client show it finished, as WaitGetResults does. The executions of a pipeline are tracked
+// when PipelineStatus lists them, and recorded the same way.
+//
+// Executions never seen finished, such as those launched without waiting for them, are recorded by
+// FlushUsage, which should be called before the client is dropped. Recording errors do not fail the
+// calls of the client, they are returned by FlushUsage.
+func (c *duneClient) WithUsageRecorder(recorder UsageRecorder) DuneClient {
+	client := c.clone()
+	client.usage = &usageTracker{
+		recorder:  recorder,
+		pending:   map[string]models.ExecutionUsage{},
+		pipelines: map[string]string{},
+		recorded:  map[string]bool{},
+	}
+	return client
+}
+
+// clone returns a copy of c, for the With methods
+func (c *duneClient) clone() *duneClient {
+	client := *c
+	return &client
+}
+
+// trackExecution starts tracking a launched execution, if c records usage and has not recorded it yet
+func (c *duneClient) trackExecution(usage models.ExecutionUsage) {
+	if c.usage == nil {
+		return
+	}
+	usage.SubmittedAt = time.Now().UTC()
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	if c.usage.recorded[usage.ExecutionID] {
+		return
+	}
+	if _, ok := c.usage.pending[usage.ExecutionID]; !ok {
+		c.usage.pending[usage.ExecutionID] = usage
+	}
+}
+
+// updateExecution updates the last state seen of a tracked execution, and records it if the state is final
+func (c *duneClient) updateExecution(executionID, state string, startedAt, endedAt *time.Time,
+	metadata *models.ResultMetadata, credits *float64,
+) {
+	if c.usage == nil {
+		return
+	}
+	c.usage.mu.Lock()
+	usage, ok := c.usage.pending[executionID]
+	if !ok {
+		c.usage.mu.Unlock()
+		return
+	}
+	usage.State = state
+	usage.ExecutionStartedAt, usage.ExecutionEndedAt = startedAt, endedAt
+	if startedAt != nil && endedAt != nil {
+		usage.RuntimeSeconds = endedAt.Sub(*startedAt).Seconds()
+	}
+	if metadata != nil {
+		usage.ResultSetBytes = metadata.ResultSetBytes
+		if metadata.TotalResultSetBytes > 0 {
+			usage.ResultSetBytes = metadata.TotalResultSetBytes
+		}
+		usage.DatapointCount = metadata.DatapointCount
+	}
+	usage.Credits = credits
+	if !finalState(state) {
+		c.usage.pending[executionID] = usage
+		c.usage.mu.Unlock()
+		return
+	}
+	delete(c.usage.pending, executionID)
+	c.usage.recorded[executionID] = true
+	c.usage.mu.Unlock()
+	c.recordExecution(usage)
+}
+
+// recordExecution records an execution, keeping the error for FlushUsage
+func (c *duneClient) recordExecution(usage models.ExecutionUsage) {
+	if err := c.usage.recorder.RecordExecution(usage); err != nil {
+		c.usage.mu.Lock()
+		defer c.usage.mu.Unlock()
+		c.usage.errs = append(c.usage.errs, fmt.Errorf("failed to record execution %s: %w", usage.ExecutionID, err))
+	}
+}
+
+// FlushUsage records the executions launched by c that were not seen finished yet. Their status is
+// read first, so those that finished in the meantime are recorded with their resources, and the others
+// are recorded with the last state seen. It returns the errors met reading these statuses and the
+// recording errors met since the last call. FlushUsage does nothing on a client without a usage recorder.
+func (c *duneClient) FlushUsage() error {
+	if c.usage == nil {
+		return nil
+	}
+	c.usage.mu.Lock()
+	executionIDs := make([]string, 0, len(c.usage.pending))
+	for executionID := range c.usage.pending {
+		executionIDs = append(executionIDs, executionID)
+	}
+	c.usage.mu.Unlock()
+	slices.Sort(executionIDs)
+
+	var errs []error
+	for _, executionID := range executionIDs {
+		// QueryStatus records the execution if it finished
+		if _, err := c.QueryStatus(executionID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the status of execution %s: %w", executionID, err))
+		}
+		c.usage.mu.Lock()
+		usage, ok := c.usage.pending[executionID]
+		delete(c.usage.pending, executionID)
+		if ok {
+			c.usage.recorded[executionID] = true
+		}
+		c.usage.mu.Unlock()
+		if ok {
+			c.recordExecution(usage)
+		}
+	}
+
+	c.usage.mu.Lock()
+	errs = append(errs, c.usage.errs...)
+	c.usage.errs = nil
+	c.usage.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// finalState reports whether an execution state is final, with or without the QUERY_STATE_ prefix
+// used by pipeline nodes
+func finalState(state string) bool {
+	switch strings.TrimPrefix(strings.ToUpper(state), "QUERY_STATE_") {
+	case "COMPLETED", "FAILED", "CANCELLED", "EXPIRED":
+		return true
+	}
+	return false
+}
+
+// trackPipeline starts tracking a launched pipeline, if c records usage
+func (c *duneClient) trackPipeline(pipelineExecutionID, performance string) {
+	if c.usage == nil {
+		return
+	}
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	c.usage.pipelines[pipelineExecutionID] = performance
+}
+
+// trackPipelineNodes tracks the executions listed by the status of a pipeline. Pipeline nodes do not
+// report their resources, so the executions are recorded once their own status or results are read,
+// at the latest by FlushUsage.
+func (c *duneClient) trackPipelineNodes(pipelineExecutionID string, status *models.PipelineStatusResponse) {
+	if c.usage == nil {
+		return
+	}
+	c.usage.mu.Lock()
+	performance, ok := c.usage.pipelines[pipelineExecutionID]
+	c.usage.mu.Unlock()
+	if !ok {
+		return
+	}
+	for _, node := range status.NodeExecutions {
+		nodeStatus := node.QueryExecutionStatus
+		if nodeStatus.ExecutionID == "" {
+			continue
+		}
+		c.trackExecution(models.ExecutionUsage{
+			ExecutionID:         nodeStatus.ExecutionID,
+			QueryID:             nodeStatus.QueryID,
+			Performance:         performance,
+			PipelineExecutionID: pipelineExecutionID,
+			State:               nodeStatus.Status,
+		})
+	}
+}
+
+// contentHash returns a short hash of v encoded as JSON, empty for empty values
+func contentHash(v any) string {
+	content, err := json.Marshal(v)
+	if err != nil || string(content) == "null" || string(content) == `""` || string(content) == "{}" {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// MemoryUsageRecorder keeps the recorded usage in memory. It is safe for concurrent use.
+type MemoryUsageRecorder struct {
+	mu      sync.Mutex
+	records []models.ExecutionUsage
+}
+
+func (r *MemoryUsageRecorder) RecordExecution(usage models.ExecutionUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, usage)
+	return nil
+}
+
+// Records returns the usage recorded so far
+func (r *MemoryUsageRecorder) Records() []models.ExecutionUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.records)
+}
+
+// FileUsageRecorder appends the recorded usage to a file, a JSON object per line, which can be read
+// back with ReadUsageRecords. It is safe for concurrent use.
+type FileUsageRecorder struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileUsageRecorder returns a recorder appending to the file at path, created if missing
+func NewFileUsageRecorder(path string) *FileUsageRecorder {
+	return &FileUsageRecorder{path: path}
+}
+
+func (r *FileUsageRecorder) RecordExecution(usage models.ExecutionUsage) error {
+	line, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadUsageRecords reads the usage written by a FileUsageRecorder
+func ReadUsageRecords(r io.Reader) ([]models.ExecutionUsage, error) {
+	var records []models.ExecutionUsage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var usage models.ExecutionUsage
+		if err := json.Unmarshal(scanner.Bytes(), &usage); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, usage)
+	}
+	return records, scanner.Err()
+}
+
+// usageCSVHeader are the columns written by WriteUsageCSV
+var usageCSVHeader = []string{
+	"execution_id", "query_id", "parameters_hash", "sql_hash", "performance", "pipeline_execution_id", "state",
+	"submitted_at", "execution_started_at", "execution_ended_at", "runtime_seconds", "result_set_bytes",
+	"datapoint_count", "credits",
+}
+
+// WriteUsageCSV writes usage records as CSV with a header. Missing times and credits are empty.
+func WriteUsageCSV(w io.Writer, records []models.ExecutionUsage) error {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write(usageCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		credits := ""
+		if r.Credits != nil {
+			credits = strconv.FormatFloat(*r.Credits, 'f', -1, 64)
+		}
+		err := out.Write([]string{
+			r.ExecutionID, strconv.Itoa(r.QueryID), r.ParametersHash, r.SQLHash, r.Performance,
+			r.PipelineExecutionID, r.State, r.SubmittedAt.Format(time.RFC3339), formatTime(r.ExecutionStartedAt),
+			formatTime(r.ExecutionEndedAt), strconv.FormatFloat(r.RuntimeSeconds, 'f', -1, 64),
+			strconv.FormatInt(r.ResultSetBytes, 10), strconv.Itoa(r.DatapointCount), credits,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteUsageJSON writes usage records as an indented JSON array
+func WriteUsageJSON(w io.Writer, records []models.ExecutionUsage) error {
+	if records == nil {
+		records = []models.ExecutionUsage{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// UsageGroup tells SummarizeUsage how to group executions
+type UsageGroup string
+
+const (
+	UsageByQuery       UsageGroup = "query"
+	UsageByPerformance UsageGroup = "performance"
+	UsageByDay         UsageGroup = "day"
+)
+
+// usageKey returns the key of an execution in a group
+func usageKey(usage models.ExecutionUsage, by UsageGroup) string {
+	switch by {
+	case UsageByPerformance:
+		if usage.Performance == "" {
+			return "default"
+		}
+		return usage.Performance
+	case UsageByDay:
+		return usage.SubmittedAt.UTC().Format("2006-01-02")
+	}
+	if usage.QueryID == 0 {
+		return "sql:" + usage.SQLHash
+	}
+	return strconv.Itoa(usage.QueryID)
+}
+
+// SummarizeUsage totals usage records by group, the most expensive groups first: by credits, then
+// by runtime. Raw SQL executions are grouped by query as sql:<hash of the SQL>.
+func SummarizeUsage(records []models.ExecutionUsage, by UsageGroup) []models.UsageSummary {
+	index := map[string]int{}
+	var summaries []models.UsageSummary
+	for _, r := range records {
+		key := usageKey(r, by)
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, models.UsageSummary{Key: key})
+		}
+		s := &summaries[i]
+		s.Executions++
+		switch strings.TrimPrefix(strings.ToUpper(r.State), "QUERY_STATE_") {
+		case "COMPLETED":
+		case "FAILED", "CANCELLED", "EXPIRED":
+			s.Failed++
+		default:
+			s.Unfinished++
+		}
+		s.RuntimeSeconds += r.RuntimeSeconds
+		s.ResultSetBytes += r.ResultSetBytes
+		s.DatapointCount += r.DatapointCount
+		if r.Credits != nil {
+			s.Credits += *r.Credits
+		} else {
+			s.UnknownCredits++
+		}
+	}
+	slices.SortStableFunc(summaries, func(a, b models.UsageSummary) int {
+		switch {
+		case a.Credits != b.Credits:
+			if a.Credits > b.Credits {
+				return -1
+			}
+			return 1
+		case a.RuntimeSeconds > b.RuntimeSeconds:
+			return -1
+		case a.RuntimeSeconds < b.RuntimeSeconds:
+			return 1
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return summaries
+}
@@ -0,0 +1,249 @@
+package dune
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUsageRecorder(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ended := started.Add(90 * time.Second)
+	credits := 12.5
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/sql/execute":
+			json.NewEncoder(w).Encode(pendingExecution)
+		case "/api/v1/execution/01HKZJ2683PHF9Q9PHHQ8FW4Q1/results":
+			json.NewEncoder(w).Encode(models.ResultsResponse{
+				State:                "QUERY_STATE_COMPLETED",
+				ExecutionStartedAt:   &started,
+				ExecutionEndedAt:     &ended,
+				IsExecutionFinished:  true,
+				ExecutionCostCredits: &credits,
+				Result: models.Result{
+					Metadata: models.ResultMetadata{
+						RowCount: 1, ResultSetBytes: 10, TotalResultSetBytes: 10, DatapointCount: 2,
+					},
+					Rows: []map[string]any{{"a": 1, "b": 2}},
+				},
+			})
+		case "/api/v1/execution/01HKZJ2683PHF9Q9PHHQ8FW4Q1/status":
+			json.NewEncoder(w).Encode(models.StatusResponse{
+				ExecutionID:      "01HKZJ2683PHF9Q9PHHQ8FW4Q1",
+				State:            "QUERY_STATE_COMPLETED",
+				ExecutionEndedAt: &ended,
+				ResultMetadata:   &models.ResultMetadata{},
+			})
+		}
+	})
+
+	recorder := &MemoryUsageRecorder{}
+	recorded := client.WithUsageRecorder(recorder)
+	execution, err := recorded.RunSQL(models.ExecuteSQLRequest{
+		SQL:             "SELECT 1 AS a, 2 AS b",
+		Performance:     "large",
+		QueryParameters: map[string]any{"chain": "ethereum"},
+	})
+	require.NoError(t, err)
+	_, err = execution.WaitGetResults(time.Millisecond, 1)
+	require.NoError(t, err)
+	// executions are recorded once
+	_, err = execution.GetStatus()
+	require.NoError(t, err)
+
+	records := recorder.Records()
+	require.Len(t, records, 1)
+	usage := records[0]
+	require.Equal(t, "01HKZJ2683PHF9Q9PHHQ8FW4Q1", usage.ExecutionID)
+	require.Zero(t, usage.QueryID)
+	require.Equal(t, contentHash("SELECT 1 AS a, 2 AS b"), usage.SQLHash)
+	require.Equal(t, contentHash(map[string]any{"chain": "ethereum"}), usage.ParametersHash)
+	require.Len(t, usage.ParametersHash, 16)
+	require.Equal(t, "large", usage.Performance)
+	require.Equal(t, "QUERY_STATE_COMPLETED", usage.State)
+	require.Equal(t, 90.0, usage.RuntimeSeconds)
+	require.Equal(t, int64(10), usage.ResultSetBytes)
+	require.Equal(t, 2, usage.DatapointCount)
+	require.Equal(t, 12.5, *usage.Credits)
+	require.False(t, usage.SubmittedAt.IsZero())
+
+	// executions read through a client without the recorder are not recorded
+	_, err = client.QueryResultsV2("01HKZJ2683PHF9Q9PHHQ8FW4Q1", models.ResultOptions{})
+	require.NoError(t, err)
+	require.Len(t, recorder.Records(), 1)
+}
+
+func TestWithUsageRecorderPipeline(t *testing.T) {
+	ended := time.Now()
+	var statusCalls int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query/7/pipeline/execute":
+			json.NewEncoder(w).Encode(models.PipelineExecuteResponse{PipelineExecutionID: "pipeline-1"})
+		case "/api/v1/pipelines/executions/pipeline-1/status":
+			json.NewEncoder(w).Encode(models.PipelineStatusResponse{
+				Status: "running",
+				NodeExecutions: []models.PipelineNodeExecution{
+					{ID: 1, QueryExecutionStatus: models.PipelineQueryExecutionStatus{
+						Status: "completed", QueryID: 5, ExecutionID: "01HKZJ2683PHF9Q9PHHQ8FW4Q1",
+					}},
+					{ID: 2, QueryExecutionStatus: models.PipelineQueryExecutionStatus{
+						Status: "executing", QueryID: 7, ExecutionID: "01HKZJ2683PHF9Q9PHHQ8FW4Q2",
+					}},
+				},
+			})
+		case "/api/v1/execution/01HKZJ2683PHF9Q9PHHQ8FW4Q1/status":
+			statusCalls++
+			json.NewEncoder(w).Encode(models.StatusResponse{
+				ExecutionID:      "01HKZJ2683PHF9Q9PHHQ8FW4Q1",
+				QueryID:          5,
+				State:            "QUERY_STATE_COMPLETED",
+				ExecutionEndedAt: &ended,
+				ResultMetadata:   &models.ResultMetadata{DatapointCount: 3},
+			})
+		case "/api/v1/execution/01HKZJ2683PHF9Q9PHHQ8FW4Q2/status":
+			statusCalls++
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	})
+
+	recorder := &MemoryUsageRecorder{}
+	recorded := client.WithUsageRecorder(recorder)
+	pipeline, err := recorded.QueryPipelineExecute(models.PipelineExecuteRequest{QueryID: "7", Performance: "medium"})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = recorded.PipelineStatus(pipeline.PipelineExecutionID)
+		require.NoError(t, err)
+	}
+
+	// the status of the nodes is only read by FlushUsage
+	require.Zero(t, statusCalls)
+	require.Empty(t, recorder.Records())
+	err = recorded.FlushUsage()
+	require.ErrorContains(t, err, "failed to get the status of execution 01HKZJ2683PHF9Q9PHHQ8FW4Q2")
+	require.Equal(t, 2, statusCalls)
+
+	records := recorder.Records()
+	require.Len(t, records, 2)
+	require.Equal(t, 5, records[0].QueryID)
+	require.Equal(t, "medium", records[0].Performance)
+	require.Equal(t, "pipeline-1", records[0].PipelineExecutionID)
+	require.Equal(t, "QUERY_STATE_COMPLETED", records[0].State)
+	require.Equal(t, 3, records[0].DatapointCount)
+	require.Nil(t, records[0].Credits)
+	// the execution whose status cannot be read is recorded with the last state seen
+	require.Equal(t, 7, records[1].QueryID)
+	require.Equal(t, "executing", records[1].State)
+
+	require.NoError(t, recorded.FlushUsage())
+	require.Len(t, recorder.Records(), 2)
+}
+
+// failingRecorder fails to record any execution
+type failingRecorder struct{}
+
+func (failingRecorder) RecordExecution(models.ExecutionUsage) error {
+	return errors.New("disk full")
+}
+
+func TestFlushUsage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query/1/execute":
+			json.NewEncoder(w).Encode(pendingExecution)
+		case "/api/v1/execution/01HKZJ2683PHF9Q9PHHQ8FW4Q1/status":
+			json.NewEncoder(w).Encode(models.StatusResponse{
+				ExecutionID: "01HKZJ2683PHF9Q9PHHQ8FW4Q1",
+				State:       "QUERY_STATE_EXECUTING",
+			})
+		}
+	})
+
+	// an execution launched without waiting for it is recorded by FlushUsage
+	recorder := &MemoryUsageRecorder{}
+	recorded := client.WithUsageRecorder(recorder)
+	_, err := recorded.QueryExecute(models.ExecuteRequest{QueryID: 1})
+	require.NoError(t, err)
+	require.Empty(t, recorder.Records())
+	require.NoError(t, recorded.FlushUsage())
+	records := recorder.Records()
+	require.Len(t, records, 1)
+	require.Equal(t, 1, records[0].QueryID)
+	require.Equal(t, "QUERY_STATE_EXECUTING", records[0].State)
+
+	// recording errors are returned by FlushUsage
+	recorded = client.WithUsageRecorder(failingRecorder{})
+	_, err = recorded.QueryExecute(models.ExecuteRequest{QueryID: 1})
+	require.NoError(t, err)
+	require.EqualError(t, recorded.FlushUsage(), "failed to record execution 01HKZJ2683PHF9Q9PHHQ8FW4Q1: disk full")
+	require.NoError(t, recorded.FlushUsage())
+
+	// a client without recorder has nothing to flush
+	require.NoError(t, client.FlushUsage())
+}
+
+func TestUsageExportAndSummary(t *testing.T) {
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	one, two := 1.0, 2.5
+	records := []models.ExecutionUsage{
+		{ExecutionID: "a", QueryID: 1, State: "QUERY_STATE_COMPLETED", SubmittedAt: day, RuntimeSeconds: 10,
+			Credits: &one},
+		{ExecutionID: "b", QueryID: 2, State: "QUERY_STATE_COMPLETED", SubmittedAt: day, RuntimeSeconds: 5,
+			Credits: &two},
+		{ExecutionID: "c", QueryID: 1, State: "QUERY_STATE_FAILED", SubmittedAt: day, RuntimeSeconds: 1,
+			Performance: "large"},
+		{ExecutionID: "d", SQLHash: "abc", State: "QUERY_STATE_COMPLETED", SubmittedAt: day.AddDate(0, 0, 1),
+			ResultSetBytes: 100, DatapointCount: 4},
+		// recorded by FlushUsage while still running, neither completed nor failed
+		{ExecutionID: "e", QueryID: 2, State: "QUERY_STATE_EXECUTING", SubmittedAt: day.AddDate(0, 0, 1)},
+	}
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	recorder := NewFileUsageRecorder(path)
+	for _, r := range records {
+		require.NoError(t, recorder.RecordExecution(r))
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	read, err := ReadUsageRecords(f)
+	require.NoError(t, err)
+	require.Equal(t, records, read)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteUsageCSV(&out, records))
+	rows, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	require.Equal(t, usageCSVHeader, rows[0])
+	require.Equal(t, []string{"a", "1", "", "", "", "", "QUERY_STATE_COMPLETED", "2024-05-01T12:00:00Z", "", "",
+		"10", "0", "0", "1"}, rows[1])
+	require.Equal(t, "", rows[3][13])
+
+	out.Reset()
+	require.NoError(t, WriteUsageJSON(&out, records[:1]))
+	var decoded []models.ExecutionUsage
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Equal(t, records[:1], decoded)
+
+	require.Equal(t, []models.UsageSummary{
+		{Key: "2", Executions: 2, Unfinished: 1, RuntimeSeconds: 5, Credits: 2.5, UnknownCredits: 1},
+		{Key: "1", Executions: 2, Failed: 1, RuntimeSeconds: 11, Credits: 1, UnknownCredits: 1},
+		{Key: "sql:abc", Executions: 1, ResultSetBytes: 100, DatapointCount: 4, UnknownCredits: 1},
+	}, SummarizeUsage(records, UsageByQuery))
+	require.Equal(t, []models.UsageSummary{
+		{Key: "2024-05-01", Executions: 3, Failed: 1, RuntimeSeconds: 16, Credits: 3.5, UnknownCredits: 1},
+		{Key: "2024-05-02", Executions: 2, Unfinished: 1, ResultSetBytes: 100, DatapointCount: 4, UnknownCredits: 2},
+	}, SummarizeUsage(records, UsageByDay))
+	require.Equal(t, "default", SummarizeUsage(records, UsageByPerformance)[0].Key)
+}
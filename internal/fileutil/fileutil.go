@@ -0,0 +1,46 @@
+// Package fileutil holds the file helpers shared by the packages of the module.
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to path like os.WriteFile, but through a temporary file of the same directory
+// that is synced to disk before being renamed over path, so a crash leaves either the previous or the
+// new content of path, never a truncated file.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	// make the rename itself durable, where directories can be synced
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
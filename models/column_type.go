@@ -0,0 +1,323 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnType is the base type of a table column, without its parameters
+type ColumnType string
+
+const (
+	ColumnTypeVarchar   ColumnType = "varchar"
+	ColumnTypeVarbinary ColumnType = "varbinary"
+	ColumnTypeBoolean   ColumnType = "boolean"
+	ColumnTypeTinyint   ColumnType = "tinyint"
+	ColumnTypeSmallint  ColumnType = "smallint"
+	ColumnTypeInteger   ColumnType = "integer"
+	ColumnTypeBigint    ColumnType = "bigint"
+	ColumnTypeUint256   ColumnType = "uint256"
+	ColumnTypeInt256    ColumnType = "int256"
+	ColumnTypeReal      ColumnType = "real"
+	ColumnTypeDouble    ColumnType = "double"
+	ColumnTypeDecimal   ColumnType = "decimal"
+	ColumnTypeDate      ColumnType = "date"
+	ColumnTypeTimestamp ColumnType = "timestamp"
+	ColumnTypeJSON      ColumnType = "json"
+	ColumnTypeArray     ColumnType = "array"
+	ColumnTypeMap       ColumnType = "map"
+	ColumnTypeRow       ColumnType = "row"
+)
+
+// columnTypeAliases maps alternative spellings to their column type
+var columnTypeAliases = map[string]ColumnType{
+	"int":    ColumnTypeInteger,
+	"bool":   ColumnTypeBoolean,
+	"string": ColumnTypeVarchar,
+	"float":  ColumnTypeReal,
+}
+
+// IsInteger reports whether values of the type are whole numbers
+func (t ColumnType) IsInteger() bool {
+	switch t {
+	case ColumnTypeTinyint, ColumnTypeSmallint, ColumnTypeInteger, ColumnTypeBigint,
+		ColumnTypeUint256, ColumnTypeInt256:
+		return true
+	}
+	return false
+}
+
+// IsNumeric reports whether values of the type are numbers
+func (t ColumnType) IsNumeric() bool {
+	switch t {
+	case ColumnTypeReal, ColumnTypeDouble, ColumnTypeDecimal:
+		return true
+	}
+	return t.IsInteger()
+}
+
+// ParsedColumnType is a column type with its parameters, such as decimal(38,0),
+// array(varchar), map(varchar, integer) or row(a integer, b varchar).
+type ParsedColumnType struct {
+	Type ColumnType
+	// Precision is the precision of decimals and timestamps, or the length of varchars, -1 if not given
+	Precision int
+	// Scale is the scale of decimals
+	Scale int
+	// WithTimeZone is set for timestamps with time zone
+	WithTimeZone bool
+	// Elem is the type of array elements and map values
+	Elem *ParsedColumnType
+	// Key is the type of map keys
+	Key *ParsedColumnType
+	// Fields are the fields of rows
+	Fields []RowField
+}
+
+// RowField is a named field of a row type
+type RowField struct {
+	Name string
+	Type ParsedColumnType
+}
+
+// ParseColumnType parses a column type as reported by the API. Type names are case insensitive.
+// Unknown type names are kept as they are, only malformed types are an error.
+func ParseColumnType(s string) (ParsedColumnType, error) {
+	p := typeParser{s: s}
+	t, err := p.parse()
+	if err != nil {
+		return ParsedColumnType{}, fmt.Errorf("invalid column type %q: %w", s, err)
+	}
+	if rest := strings.TrimSpace(p.s[p.pos:]); rest != "" {
+		return ParsedColumnType{}, fmt.Errorf("invalid column type %q: unexpected %q", s, rest)
+	}
+	return t, nil
+}
+
+// BaseColumnType returns the base type of a column type, or the lower cased type if it is malformed
+func BaseColumnType(s string) ColumnType {
+	t, err := ParseColumnType(s)
+	if err != nil {
+		return ColumnType(strings.ToLower(strings.TrimSpace(s)))
+	}
+	return t.Type
+}
+
+// String formats the type the way DuneSQL does
+func (t ParsedColumnType) String() string {
+	switch t.Type {
+	case ColumnTypeDecimal:
+		if t.Precision >= 0 {
+			return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale)
+		}
+	case ColumnTypeTimestamp:
+		s := string(t.Type)
+		if t.Precision >= 0 {
+			s += fmt.Sprintf("(%d)", t.Precision)
+		}
+		if t.WithTimeZone {
+			s += " with time zone"
+		}
+		return s
+	case ColumnTypeVarchar:
+		if t.Precision >= 0 {
+			return fmt.Sprintf("varchar(%d)", t.Precision)
+		}
+	case ColumnTypeArray:
+		if t.Elem != nil {
+			return "array(" + t.Elem.String() + ")"
+		}
+	case ColumnTypeMap:
+		if t.Key != nil && t.Elem != nil {
+			return "map(" + t.Key.String() + ", " + t.Elem.String() + ")"
+		}
+	case ColumnTypeRow:
+		fields := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = f.Name + " " + f.Type.String()
+		}
+		return "row(" + strings.Join(fields, ", ") + ")"
+	}
+	return string(t.Type)
+}
+
+// typeParser is a recursive descent parser of column types
+type typeParser struct {
+	s   string
+	pos int
+}
+
+func (p *typeParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// word reads an identifier, a number or a double quoted name
+func (p *typeParser) word() (string, error) {
+	p.skipSpaces()
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		end := strings.IndexByte(p.s[p.pos+1:], '"')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted name")
+		}
+		w := p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return w, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" (),", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", fmt.Errorf("expected a name at offset %d", start)
+	}
+	return p.s[start:p.pos], nil
+}
+
+// consume skips c if it is the next character
+func (p *typeParser) consume(c byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *typeParser) expect(c byte) error {
+	if !p.consume(c) {
+		return fmt.Errorf("expected %q at offset %d", c, p.pos)
+	}
+	return nil
+}
+
+func (p *typeParser) number() (int, error) {
+	w, err := p.word()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(w)
+}
+
+// suffix skips words if they are next, case insensitively
+func (p *typeParser) suffix(words string) bool {
+	p.skipSpaces()
+	rest := p.s[p.pos:]
+	if len(rest) >= len(words) && strings.EqualFold(rest[:len(words)], words) {
+		p.pos += len(words)
+		return true
+	}
+	return false
+}
+
+func (p *typeParser) parse() (ParsedColumnType, error) {
+	name, err := p.word()
+	if err != nil {
+		return ParsedColumnType{}, err
+	}
+	t := ParsedColumnType{Type: ColumnType(strings.ToLower(name)), Precision: -1}
+	if alias, ok := columnTypeAliases[string(t.Type)]; ok {
+		t.Type = alias
+	}
+
+	switch t.Type {
+	case ColumnTypeDecimal:
+		if p.consume('(') {
+			if t.Precision, err = p.number(); err != nil {
+				return t, err
+			}
+			if p.consume(',') {
+				if t.Scale, err = p.number(); err != nil {
+					return t, err
+				}
+			}
+			if err := p.expect(')'); err != nil {
+				return t, err
+			}
+		}
+	case ColumnTypeVarchar, ColumnTypeTimestamp:
+		if p.consume('(') {
+			if t.Precision, err = p.number(); err != nil {
+				return t, err
+			}
+			if err := p.expect(')'); err != nil {
+				return t, err
+			}
+		}
+		if t.Type == ColumnTypeTimestamp && p.suffix("with time zone") {
+			t.WithTimeZone = true
+		}
+	case ColumnTypeArray:
+		if err := p.expect('('); err != nil {
+			return t, err
+		}
+		elem, err := p.parse()
+		if err != nil {
+			return t, err
+		}
+		t.Elem = &elem
+		if err := p.expect(')'); err != nil {
+			return t, err
+		}
+	case ColumnTypeMap:
+		if err := p.expect('('); err != nil {
+			return t, err
+		}
+		key, err := p.parse()
+		if err != nil {
+			return t, err
+		}
+		if err := p.expect(','); err != nil {
+			return t, err
+		}
+		elem, err := p.parse()
+		if err != nil {
+			return t, err
+		}
+		t.Key, t.Elem = &key, &elem
+		if err := p.expect(')'); err != nil {
+			return t, err
+		}
+	case ColumnTypeRow:
+		if err := p.expect('('); err != nil {
+			return t, err
+		}
+		for {
+			fieldName, err := p.word()
+			if err != nil {
+				return t, err
+			}
+			fieldType, err := p.parse()
+			if err != nil {
+				return t, err
+			}
+			t.Fields = append(t.Fields, RowField{Name: fieldName, Type: fieldType})
+			if !p.consume(',') {
+				break
+			}
+		}
+		if err := p.expect(')'); err != nil {
+			return t, err
+		}
+	default:
+		// parameters of other types, such as char(3), are not interpreted
+		if p.consume('(') {
+			depth := 1
+			for p.pos < len(p.s) && depth > 0 {
+				switch p.s[p.pos] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				p.pos++
+			}
+			if depth > 0 {
+				return t, fmt.Errorf("unbalanced parentheses")
+			}
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColumnType(t *testing.T) {
+	for input, expected := range map[string]string{
+		"integer":                                "integer",
+		"INT":                                    "integer",
+		"decimal(38,0)":                          "decimal(38,0)",
+		"decimal(38, 18)":                        "decimal(38,18)",
+		"varchar(255)":                           "varchar(255)",
+		"timestamp(3) with time zone":            "timestamp(3) with time zone",
+		"array(varchar)":                         "array(varchar)",
+		"array(array(decimal(10,2)))":            "array(array(decimal(10,2)))",
+		"map(varchar, array(bigint))":            "map(varchar, array(bigint))",
+		"row(amount uint256, \"to\" varbinary)":  "row(amount uint256, to varbinary)",
+		"row(a row(b integer), c map(int, int))": "row(a row(b integer), c map(integer, integer))",
+		"char(3)":                                "char",
+	} {
+		parsed, err := ParseColumnType(input)
+		require.NoError(t, err, input)
+		require.Equal(t, expected, parsed.String(), input)
+	}
+
+	parsed, err := ParseColumnType("map(varchar, decimal(38,0))")
+	require.NoError(t, err)
+	require.Equal(t, ColumnTypeMap, parsed.Type)
+	require.Equal(t, ColumnTypeVarchar, parsed.Key.Type)
+	require.Equal(t, ParsedColumnType{Type: ColumnTypeDecimal, Precision: 38}, *parsed.Elem)
+
+	for _, input := range []string{"", "array(varchar", "decimal(a,b)", "map(varchar)", "integer integer", "char(3"} {
+		_, err := ParseColumnType(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestBaseColumnType(t *testing.T) {
+	require.Equal(t, ColumnTypeDecimal, BaseColumnType("decimal(38,0)"))
+	require.Equal(t, ColumnTypeArray, BaseColumnType("array(varchar)"))
+	require.Equal(t, ColumnType("array(varchar"), BaseColumnType("ARRAY(varchar"))
+	require.True(t, BaseColumnType("uint256").IsInteger())
+	require.True(t, BaseColumnType("double").IsNumeric())
+	require.False(t, BaseColumnType("varchar").IsNumeric())
+}
@@ -0,0 +1,9 @@
+package models
+
+// PaginationOptions controls how the All* iterators of the client walk a paginated endpoint
+type PaginationOptions struct {
+	// PageSize is the number of items requested per page, the endpoint default if zero
+	PageSize int
+	// MaxItems stops the iteration after this many items, all items are returned if zero
+	MaxItems int
+}
@@ -1,5 +1,13 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
 type QueryParameter struct {
 	Key         string   `json:"key"`
 	Type        string   `json:"type"`
@@ -13,6 +21,7 @@ type CreateQueryRequest struct {
 	Description string           `json:"description,omitempty"`
 	IsPrivate   bool             `json:"is_private,omitempty"`
 	IsTemp      bool             `json:"is_temp,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
 	Parameters  []QueryParameter `json:"parameters,omitempty"`
 }
 
@@ -36,6 +45,8 @@ type GetQueryResponse struct {
 	Parameters  []QueryParameter `json:"parameters"`
 }
 
+// UpdateQueryRequest changes the fields of a saved query that are set. Tags and Parameters are
+// left unchanged when nil, and cleared when set to an empty slice.
 type UpdateQueryRequest struct {
 	Name        *string          `json:"name,omitempty"`
 	QuerySQL    *string          `json:"query_sql,omitempty"`
@@ -46,6 +57,75 @@ type UpdateQueryRequest struct {
 	Parameters  []QueryParameter `json:"parameters,omitempty"`
 }
 
+// MarshalJSON omits Tags and Parameters when nil but sends them when empty, so they can be cleared
+func (r UpdateQueryRequest) MarshalJSON() ([]byte, error) {
+	type request UpdateQueryRequest
+	out := struct {
+		request
+		Tags       *[]string         `json:"tags,omitempty"`
+		Parameters *[]QueryParameter `json:"parameters,omitempty"`
+	}{request: request(r)}
+	if r.Tags != nil {
+		out.Tags = &r.Tags
+	}
+	if r.Parameters != nil {
+		out.Parameters = &r.Parameters
+	}
+	return json.Marshal(out)
+}
+
 type UpdateQueryResponse struct {
 	QueryID int `json:"query_id"`
 }
+
+// ListQueriesRequest filters and paginates the saved queries returned by ListQueries
+type ListQueriesRequest struct {
+	Limit  int
+	Offset int
+	// Owner restricts the results to queries owned by this user or team handle
+	Owner string
+	// Tags restricts the results to queries having all of these tags
+	Tags []string
+	// Archived includes only archived (true) or only active (false) queries, nil returns both
+	Archived *bool
+}
+
+func (r ListQueriesRequest) ToURLValues() url.Values {
+	v := url.Values{}
+	if r.Limit > 0 {
+		v.Add("limit", fmt.Sprintf("%d", r.Limit))
+	}
+	if r.Offset > 0 {
+		v.Add("offset", fmt.Sprintf("%d", r.Offset))
+	}
+	if r.Owner != "" {
+		v.Add("owner", r.Owner)
+	}
+	if len(r.Tags) > 0 {
+		v.Add("tags", strings.Join(r.Tags, ","))
+	}
+	if r.Archived != nil {
+		v.Add("archived", fmt.Sprintf("%t", *r.Archived))
+	}
+	return v
+}
+
+type QueryListElement struct {
+	QueryID     int       `json:"query_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	IsPrivate   bool      `json:"is_private"`
+	IsArchived  bool      `json:"is_archived"`
+	IsTemp      bool      `json:"is_temp"`
+	Version     int       `json:"version"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ListQueriesResponse struct {
+	Queries    []QueryListElement `json:"queries"`
+	Total      int                `json:"total"`
+	NextOffset *int               `json:"next_offset,omitempty"`
+}
@@ -38,6 +38,8 @@ type ResultsResponse struct {
 	NextOffset          *uint64         `json:"next_offset,omitempty"`
 	NextURI             *string         `json:"next_uri,omitempty"`
 	IsExecutionFinished bool            `json:"is_execution_finished,omitempty"`
+	// ExecutionCostCredits is the cost of the execution, when reported by the API
+	ExecutionCostCredits *float64 `json:"execution_cost_credits,omitempty"`
 }
 
 func (r ResultsResponse) HasError() error {
@@ -91,6 +93,7 @@ func (r *ResultsResponse) AddPageResult(pageResp *ResultsResponse) {
 		r.NextOffset = pageResp.NextOffset
 		r.NextURI = pageResp.NextURI
 		r.IsExecutionFinished = pageResp.IsExecutionFinished
+		r.ExecutionCostCredits = pageResp.ExecutionCostCredits
 		// re-use full result from first page
 		r.Result = pageResp.Result
 	} else {
@@ -100,6 +103,7 @@ func (r *ResultsResponse) AddPageResult(pageResp *ResultsResponse) {
 		r.Result.Metadata.RowCount += pageResp.Result.Metadata.RowCount
 		r.Result.Metadata.DatapointCount += pageResp.Result.Metadata.DatapointCount
 		r.IsExecutionFinished = pageResp.IsExecutionFinished
+		r.ExecutionCostCredits = pageResp.ExecutionCostCredits
 		r.NextOffset = pageResp.NextOffset
 	}
 }
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// DatasetSchema is the typed form of SearchDatasetResult.Schema
+type DatasetSchema struct {
+	Columns []SchemaColumn
+	// PartitionedBy lists the partition columns, also flagged with SchemaColumn.Partition
+	PartitionedBy []string
+	// Raw is the schema as returned by the API
+	Raw json.RawMessage
+}
+
+// SchemaColumn is a column of a dataset schema
+type SchemaColumn struct {
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Nullable    *bool          `json:"nullable,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	// Partition is set for the columns listed in DatasetSchema.PartitionedBy
+	Partition bool `json:"-"`
+}
+
+// ParsedType parses the type of the column
+func (c SchemaColumn) ParsedType() (ParsedColumnType, error) {
+	return ParseColumnType(c.Type)
+}
+
+// UnmarshalJSON decodes a schema given either as an array of columns or as an object with
+// a columns array and the partition columns in partitioned_by.
+func (s *DatasetSchema) UnmarshalJSON(data []byte) error {
+	*s = DatasetSchema{Raw: append(json.RawMessage{}, data...)}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(data, &s.Columns); err != nil {
+			return fmt.Errorf("invalid dataset schema: %w", err)
+		}
+		return nil
+	}
+	var object struct {
+		Columns       []SchemaColumn `json:"columns"`
+		PartitionedBy []string       `json:"partitioned_by"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("invalid dataset schema: %w", err)
+	}
+	s.Columns, s.PartitionedBy = object.Columns, object.PartitionedBy
+	for i, column := range s.Columns {
+		s.Columns[i].Partition = slices.Contains(s.PartitionedBy, column.Name)
+	}
+	return nil
+}
+
+// MarshalJSON returns the raw schema, so a decoded schema encodes as it was received
+func (s DatasetSchema) MarshalJSON() ([]byte, error) {
+	if len(s.Raw) == 0 {
+		return []byte("null"), nil
+	}
+	return s.Raw, nil
+}
+
+// Column returns the column with the given name, or false
+func (s DatasetSchema) Column(name string) (SchemaColumn, bool) {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return SchemaColumn{}, false
+}
+
+// DecodeSchema decodes the schema of the result, nil if the search did not include it
+func (r SearchDatasetResult) DecodeSchema() (*DatasetSchema, error) {
+	if len(r.Schema) == 0 {
+		return nil, nil
+	}
+	var schema DatasetSchema
+	if err := json.Unmarshal(r.Schema, &schema); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.FullName, err)
+	}
+	return &schema, nil
+}
+
+// SpellMetadata is the typed form of SearchDatasetMetadata.SpellMetadata
+type SpellMetadata struct {
+	// Dependencies are the datasets the spell is built from
+	Dependencies []string
+	// RefreshStrategy is how the spell is refreshed, such as incremental or full
+	RefreshStrategy string
+	// Materialization is how the spell is stored, such as table, view or incremental
+	Materialization string
+	// UniqueKey lists the columns identifying the rows of incremental spells
+	UniqueKey []string
+	// PartitionedBy lists the partition columns
+	PartitionedBy []string
+	Tags          []string
+	Owners        []string
+	// Raw is the metadata as returned by the API
+	Raw json.RawMessage
+}
+
+// UnmarshalJSON decodes spell metadata
+func (m *SpellMetadata) UnmarshalJSON(data []byte) error {
+	*m = SpellMetadata{Raw: append(json.RawMessage{}, data...)}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	var fields struct {
+		Dependencies    []string `json:"dependencies"`
+		RefreshStrategy string   `json:"refresh_strategy"`
+		Materialization string   `json:"materialization"`
+		UniqueKey       []string `json:"unique_key"`
+		PartitionedBy   []string `json:"partitioned_by"`
+		Tags            []string `json:"tags"`
+		Owners          []string `json:"owners"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("invalid spell metadata: %w", err)
+	}
+	m.Dependencies = fields.Dependencies
+	m.RefreshStrategy = fields.RefreshStrategy
+	m.Materialization = fields.Materialization
+	m.UniqueKey = fields.UniqueKey
+	m.PartitionedBy = fields.PartitionedBy
+	m.Tags = fields.Tags
+	m.Owners = fields.Owners
+	return nil
+}
+
+// MarshalJSON returns the raw metadata, so decoded metadata encodes as it was received
+func (m SpellMetadata) MarshalJSON() ([]byte, error) {
+	if len(m.Raw) == 0 {
+		return []byte("null"), nil
+	}
+	return m.Raw, nil
+}
+
+// DecodeSpellMetadata decodes the spell metadata, nil if the dataset has none
+func (m SearchDatasetMetadata) DecodeSpellMetadata() (*SpellMetadata, error) {
+	if len(m.SpellMetadata) == 0 {
+		return nil, nil
+	}
+	var spell SpellMetadata
+	if err := json.Unmarshal(m.SpellMetadata, &spell); err != nil {
+		return nil, err
+	}
+	return &spell, nil
+}
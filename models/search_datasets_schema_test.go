@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeSchemaArray(t *testing.T) {
+	raw := `[{"name": "block_time", "type": "timestamp(3) with time zone", "nullable": false},
+		{"name": "amount", "type": "decimal(38,0)", "description": "Raw amount"}]`
+
+	schema, err := SearchDatasetResult{FullName: "dex.trades", Schema: json.RawMessage(raw)}.DecodeSchema()
+
+	require.NoError(t, err)
+	require.Len(t, schema.Columns, 2)
+	require.False(t, *schema.Columns[0].Nullable)
+	amount, ok := schema.Column("amount")
+	require.True(t, ok)
+	require.Equal(t, "decimal(38,0)", amount.Type)
+	require.Equal(t, "Raw amount", amount.Description)
+	require.Nil(t, amount.Nullable)
+	parsed, err := amount.ParsedType()
+	require.NoError(t, err)
+	require.Equal(t, 38, parsed.Precision)
+	require.JSONEq(t, raw, string(schema.Raw))
+
+	encoded, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.JSONEq(t, raw, string(encoded))
+}
+
+func TestDecodeSchemaObject(t *testing.T) {
+	raw := `{"columns": [{"name": "day", "type": "date", "nullable": true}, {"name": "x", "type": "bigint"}],
+		"partitioned_by": ["day"]}`
+
+	schema, err := SearchDatasetResult{Schema: json.RawMessage(raw)}.DecodeSchema()
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"day"}, schema.PartitionedBy)
+	require.True(t, schema.Columns[0].Partition)
+	require.True(t, *schema.Columns[0].Nullable)
+	require.False(t, schema.Columns[1].Partition)
+
+	schema, err = SearchDatasetResult{}.DecodeSchema()
+	require.NoError(t, err)
+	require.Nil(t, schema)
+
+	_, err = SearchDatasetResult{FullName: "a.b", Schema: json.RawMessage(`"columns"`)}.DecodeSchema()
+	require.ErrorContains(t, err, "a.b: invalid dataset schema")
+}
+
+func TestDecodeSpellMetadata(t *testing.T) {
+	raw := `{"dependencies": ["dex.base_trades", "prices.usd"], "refresh_strategy": "merge",
+		"materialization": "incremental", "unique_key": ["tx_hash", "evt_index"],
+		"partitioned_by": ["block_month"], "owners": ["hildobby"]}`
+
+	spell, err := SearchDatasetMetadata{SpellMetadata: json.RawMessage(raw)}.DecodeSpellMetadata()
+
+	require.NoError(t, err)
+	require.Equal(t, &SpellMetadata{
+		Dependencies:    []string{"dex.base_trades", "prices.usd"},
+		RefreshStrategy: "merge",
+		Materialization: "incremental",
+		UniqueKey:       []string{"tx_hash", "evt_index"},
+		PartitionedBy:   []string{"block_month"},
+		Owners:          []string{"hildobby"},
+		Raw:             json.RawMessage(raw),
+	}, spell)
+
+	// the typed form can be embedded and re-encoded without losing unknown fields
+	var decoded struct {
+		Spell SpellMetadata `json:"spell"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"spell": {"dependencies": ["a"], "custom": 1}}`), &decoded))
+	require.Equal(t, []string{"a"}, decoded.Spell.Dependencies)
+	encoded, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"spell": {"dependencies": ["a"], "custom": 1}}`, string(encoded))
+
+	spell, err = SearchDatasetMetadata{}.DecodeSpellMetadata()
+	require.NoError(t, err)
+	require.Nil(t, spell)
+}
@@ -17,6 +17,8 @@ type StatusResponse struct {
 	CancelledAt        *time.Time      `json:"cancelled_at,omitempty"`
 	Error              *ExecutionError `json:"error,omitempty"`
 	ResultMetadata     *ResultMetadata `json:"result_metadata,omitempty"`
+	// ExecutionCostCredits is the cost of the execution, when reported by the API
+	ExecutionCostCredits *float64 `json:"execution_cost_credits,omitempty"`
 }
 
 func (s StatusResponse) HasError() error {
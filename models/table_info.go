@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableInfo describes a table with typed fields, whether it comes from the uploads or the datasets API
+type TableInfo struct {
+	FullName  string
+	IsPrivate bool
+	// Type is the dataset type, empty for tables listed as uploads
+	Type string
+	// SizeBytes is the size of uploaded tables, 0 if unknown
+	SizeBytes int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PurgedAt  *time.Time
+	// Owner is nil when not reported
+	Owner    *TableOwner
+	Columns  []TableColumn
+	Metadata map[string]any
+}
+
+// TableOwner is the user or team owning a table
+type TableOwner struct {
+	Handle string
+	Type   string
+}
+
+// TableColumn is a table column with its parsed type
+type TableColumn struct {
+	Name string
+	// Type is the parsed type. Types that cannot be parsed are kept as their lower cased base type.
+	Type        ParsedColumnType
+	Nullable    bool
+	Description string
+	Metadata    map[string]any
+}
+
+func tableColumn(name, columnType string, nullable bool, description string, metadata map[string]any) TableColumn {
+	parsed, err := ParseColumnType(columnType)
+	if err != nil {
+		parsed = ParsedColumnType{Type: BaseColumnType(columnType), Precision: -1}
+	}
+	return TableColumn{
+		Name:        name,
+		Type:        parsed,
+		Nullable:    nullable,
+		Description: description,
+		Metadata:    metadata,
+	}
+}
+
+// SizeBytes returns the table size, reported by the API as a string. An empty size is 0.
+func (u UploadsListElement) SizeBytes() (int64, error) {
+	if strings.TrimSpace(u.TableSizeBytes) == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(u.TableSizeBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid table size %q: %w", u.TableSizeBytes, err)
+	}
+	return size, nil
+}
+
+// TableInfo returns the typed description of an uploaded table
+func (u UploadsListElement) TableInfo() (TableInfo, error) {
+	size, err := u.SizeBytes()
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("%s: %w", u.FullName, err)
+	}
+	info := TableInfo{
+		FullName:  u.FullName,
+		IsPrivate: u.IsPrivate,
+		SizeBytes: size,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		PurgedAt:  u.PurgedAt,
+		Columns:   make([]TableColumn, 0, len(u.Columns)),
+	}
+	if u.Owner != (UploadsOwner{}) {
+		info.Owner = &TableOwner{Handle: u.Owner.Handle, Type: u.Owner.Type}
+	}
+	for _, c := range u.Columns {
+		info.Columns = append(info.Columns, tableColumn(c.Name, c.Type, c.Nullable, c.Description, c.Metadata))
+	}
+	return info, nil
+}
+
+// parseAPITime parses the timestamps the datasets API reports as strings. An empty string is the zero time.
+func parseAPITime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// TableInfo returns the typed description of a dataset
+func (d DatasetResponse) TableInfo() (TableInfo, error) {
+	createdAt, err := parseAPITime(d.CreatedAt)
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("%s: created_at: %w", d.FullName, err)
+	}
+	updatedAt, err := parseAPITime(d.UpdatedAt)
+	if err != nil {
+		return TableInfo{}, fmt.Errorf("%s: updated_at: %w", d.FullName, err)
+	}
+	info := TableInfo{
+		FullName:  d.FullName,
+		IsPrivate: d.IsPrivate,
+		Type:      d.Type,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Columns:   make([]TableColumn, 0, len(d.Columns)),
+		Metadata:  d.Metadata,
+	}
+	if d.Owner != nil {
+		info.Owner = &TableOwner{Handle: d.Owner.Handle, Type: d.Owner.Type}
+	}
+	for _, c := range d.Columns {
+		info.Columns = append(info.Columns, tableColumn(c.Name, c.Type, c.Nullable, c.Description, c.Metadata))
+	}
+	return info, nil
+}
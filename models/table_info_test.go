@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadsListElementTableInfo(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	info, err := UploadsListElement{
+		FullName:       "dune.ns.t",
+		TableSizeBytes: "1048576",
+		CreatedAt:      created,
+		Owner:          UploadsOwner{Handle: "ns", Type: "user"},
+		Columns:        []UploadsColumn{{Name: "amount", Type: "decimal(38,0)", Nullable: true}},
+	}.TableInfo()
+
+	require.NoError(t, err)
+	require.Equal(t, int64(1048576), info.SizeBytes)
+	require.Equal(t, created, info.CreatedAt)
+	require.Equal(t, &TableOwner{Handle: "ns", Type: "user"}, info.Owner)
+	require.Equal(t, ColumnTypeDecimal, info.Columns[0].Type.Type)
+	require.Equal(t, 38, info.Columns[0].Type.Precision)
+	require.True(t, info.Columns[0].Nullable)
+
+	_, err = UploadsListElement{FullName: "dune.ns.t", TableSizeBytes: "1 MB"}.TableInfo()
+	require.ErrorContains(t, err, `dune.ns.t: invalid table size "1 MB"`)
+}
+
+func TestDatasetResponseTableInfo(t *testing.T) {
+	info, err := DatasetResponse{
+		FullName:  "dex.trades",
+		Type:      "spell",
+		CreatedAt: "2024-01-01T00:00:00Z",
+		UpdatedAt: "2024-06-01T12:30:00.123456Z",
+		Columns:   []DatasetColumn{{Name: "tags", Type: "array(varchar)"}, {Name: "odd", Type: "array(varchar"}},
+	}.TableInfo()
+
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), info.CreatedAt)
+	require.Equal(t, time.Date(2024, 6, 1, 12, 30, 0, 123456000, time.UTC), info.UpdatedAt)
+	require.Nil(t, info.Owner)
+	require.Equal(t, "array(varchar)", info.Columns[0].Type.String())
+	require.Equal(t, ColumnType("array(varchar"), info.Columns[1].Type.Type)
+
+	_, err = DatasetResponse{FullName: "dex.trades", CreatedAt: "yesterday"}.TableInfo()
+	require.ErrorContains(t, err, "dex.trades: created_at: invalid timestamp")
+}
@@ -4,6 +4,17 @@ import (
 	"time"
 )
 
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// DefaultInsertChunkBytes is the default size cap of each request sent by InsertIntoUploadReader
+	DefaultInsertChunkBytes = 8 << 20
+
+	// DefaultMaxReplaceRows is the default number of rows above which UpsertReplace refuses a table
+	DefaultMaxReplaceRows = 100_000
+)
+
 type UploadsColumn struct {
 	Name        string         `json:"name"`
 	Type        string         `json:"type"`
@@ -30,6 +41,8 @@ type UploadsListElement struct {
 
 type UploadsListResponse struct {
 	Tables []UploadsListElement `json:"tables"`
+	// NextOffset is the offset of the next page, absent on the last page
+	NextOffset *int `json:"next_offset,omitempty"`
 }
 
 type UploadsCreateRequest struct {
@@ -40,6 +53,12 @@ type UploadsCreateRequest struct {
 	IsPrivate   bool            `json:"is_private,omitempty"`
 }
 
+// UploadStructsOptions sets how UploadStructs creates the table when it does not exist yet
+type UploadStructsOptions struct {
+	Description string
+	IsPrivate   bool
+}
+
 type UploadsCreateResponse struct {
 	Namespace      string `json:"namespace"`
 	TableName      string `json:"table_name"`
@@ -76,3 +95,57 @@ type UploadsDeleteResponse struct {
 type UploadsClearResponse struct {
 	Message string `json:"message"`
 }
+
+// InsertOptions controls how InsertIntoUploadReader splits its input and sends it
+type InsertOptions struct {
+	// MaxChunkBytes caps the size of each request body, DefaultInsertChunkBytes if zero
+	MaxChunkBytes int
+	// Concurrency is the number of chunks sent in parallel, chunks are sent sequentially if zero or one
+	Concurrency int
+	// Validate checks every row against the table schema before the first chunk is sent, so invalid
+	// input is not written at all. Input that cannot be rewound is copied to a temporary file meanwhile.
+	Validate bool
+}
+
+// ReplaceOptions controls how ReplaceUpload loads the new content of a table
+type ReplaceOptions struct {
+	InsertOptions
+	// StagingTableName is the table loaded and checked before the target is touched.
+	// Defaults to <table>_staging_<unix time>.
+	StagingTableName string
+}
+
+// UpsertMode tells UpsertIntoUpload what to do with input rows whose key already exists in the table
+type UpsertMode string
+
+const (
+	// UpsertSkip drops the input rows whose key already exists in the table
+	UpsertSkip UpsertMode = "skip"
+	// UpsertReplace replaces the existing rows with the input rows of the same key. The whole table
+	// is read with a SQL execution and written again through ReplaceUpload, so both the credits and
+	// the time spent grow with the table: tables above UpsertOptions.MaxReplaceRows are refused.
+	UpsertReplace UpsertMode = "replace"
+)
+
+// UpsertOptions controls how UpsertIntoUpload deduplicates its input
+type UpsertOptions struct {
+	InsertOptions
+	// KeyColumns are the columns identifying a row
+	KeyColumns []string
+	// Mode defaults to UpsertSkip
+	Mode UpsertMode
+	// MaxReplaceRows is the largest table UpsertReplace rewrites, DefaultMaxReplaceRows if zero
+	MaxReplaceRows int
+}
+
+// UpsertResponse reports what UpsertIntoUpload did with the input rows
+type UpsertResponse struct {
+	UploadsInsertResponse
+	// RowsInserted is the number of input rows with a key not yet in the table
+	RowsInserted int64 `json:"rows_inserted"`
+	// RowsReplaced is the number of input rows that replaced a row of the table, with UpsertReplace
+	RowsReplaced int64 `json:"rows_replaced"`
+	// RowsSkipped is the number of input rows not written, because their key was already in the
+	// table with UpsertSkip, or was repeated in the input
+	RowsSkipped int64 `json:"rows_skipped"`
+}
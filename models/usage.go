@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UsageRequest struct {
 	StartDate *string `json:"start_date,omitempty"`
 	EndDate   *string `json:"end_date,omitempty"`
@@ -19,3 +21,105 @@ type UsageResponse struct {
 	BytesAllowed      int64           `json:"bytes_allowed"`
 	BillingPeriods    []BillingPeriod `json:"billing_periods"`
 }
+
+// BudgetOptions sets the credit thresholds past which a budgeted client refuses to start executions
+type BudgetOptions struct {
+	// MaxCredits is the number of credits of the current billing period past which executions are
+	// refused, no limit if zero
+	MaxCredits float64
+	// MaxPercent is the share of the included credits, from 0 to 100, past which executions are
+	// refused, no limit if zero
+	MaxPercent float64
+	// CacheTTL is how long the usage is reused between checks, DefaultBudgetCacheTTL if zero
+	CacheTTL time.Duration
+	// AllowOnUsageError lets executions through when the usage cannot be fetched, or holds no billing
+	// period containing the current time, instead of refusing them
+	AllowOnUsageError bool
+}
+
+// DefaultBudgetCacheTTL is how long usage is cached by a budgeted client when BudgetOptions.CacheTTL is zero
+const DefaultBudgetCacheTTL = time.Minute
+
+// ExecutionUsage is the resources used by an execution, as recorded by a client with a usage recorder
+type ExecutionUsage struct {
+	ExecutionID string `json:"execution_id"`
+	// QueryID is zero for raw SQL executions
+	QueryID int `json:"query_id,omitempty"`
+	// ParametersHash identifies the query parameters of the execution, empty if it had none
+	ParametersHash string `json:"parameters_hash,omitempty"`
+	// SQLHash identifies the SQL of raw SQL executions
+	SQLHash     string `json:"sql_hash,omitempty"`
+	Performance string `json:"performance,omitempty"`
+	// PipelineExecutionID is set for the executions of a query pipeline
+	PipelineExecutionID string     `json:"pipeline_execution_id,omitempty"`
+	State               string     `json:"state"`
+	SubmittedAt         time.Time  `json:"submitted_at"`
+	ExecutionStartedAt  *time.Time `json:"execution_started_at,omitempty"`
+	ExecutionEndedAt    *time.Time `json:"execution_ended_at,omitempty"`
+	// RuntimeSeconds is the time between the start and the end of the execution
+	RuntimeSeconds float64 `json:"runtime_seconds"`
+	ResultSetBytes int64   `json:"result_set_bytes"`
+	DatapointCount int     `json:"datapoint_count"`
+	// Credits is nil when the API did not report the cost of the execution
+	Credits *float64 `json:"credits,omitempty"`
+}
+
+// UsageSummary totals the usage of a group of executions
+type UsageSummary struct {
+	// Key is the value the executions are grouped by
+	Key        string `json:"key"`
+	Executions int    `json:"executions"`
+	// Failed counts the executions that failed, were cancelled or expired
+	Failed int `json:"failed"`
+	// Unfinished counts the executions recorded before reaching a final state, such as by FlushUsage
+	Unfinished     int     `json:"unfinished"`
+	RuntimeSeconds float64 `json:"runtime_seconds"`
+	ResultSetBytes int64   `json:"result_set_bytes"`
+	DatapointCount int     `json:"datapoint_count"`
+	Credits        float64 `json:"credits"`
+	// UnknownCredits counts the executions whose cost was not reported, so Credits is a lower bound
+	UnknownCredits int `json:"unknown_credits"`
+}
+
+// UsageSnapshot is the usage of the current billing period and the storage at a point in time
+type UsageSnapshot struct {
+	TakenAt      time.Time     `json:"taken_at"`
+	Period       BillingPeriod `json:"period"`
+	BytesUsed    int64         `json:"bytes_used"`
+	BytesAllowed int64         `json:"bytes_allowed"`
+}
+
+// Burn rate sources of a UsageForecast
+const (
+	// BurnRateSnapshots is a burn rate measured between snapshots of the period
+	BurnRateSnapshots = "snapshots"
+	// BurnRatePeriodAverage is the average burn rate since the start of the period, used until two
+	// snapshots of the period are available
+	BurnRatePeriodAverage = "period_average"
+)
+
+// UsageForecast projects the credits used at the end of the billing period and when the storage
+// quota is exhausted, from the trend of usage snapshots
+type UsageForecast struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// TakenAt is when the latest snapshot the forecast is based on was taken
+	TakenAt         time.Time     `json:"taken_at"`
+	Period          BillingPeriod `json:"period"`
+	CreditsUsed     float64       `json:"credits_used"`
+	CreditsIncluded int           `json:"credits_included"`
+	// CreditsPerDay is the burn rate, measured as told by BurnRateSource
+	CreditsPerDay  float64 `json:"credits_per_day"`
+	BurnRateSource string  `json:"burn_rate_source"`
+	// ProjectedCredits is the credits used at the end of the period if the burn rate holds
+	ProjectedCredits float64 `json:"projected_credits"`
+	// ProjectedPercent is ProjectedCredits as a share of the included credits, zero if none are included
+	ProjectedPercent float64 `json:"projected_percent"`
+	// ProjectedOverage is the projected credits beyond the included ones
+	ProjectedOverage float64 `json:"projected_overage"`
+	BytesUsed        int64   `json:"bytes_used"`
+	BytesAllowed     int64   `json:"bytes_allowed"`
+	// BytesPerDay is the storage growth between snapshots, nil until two snapshots are available
+	BytesPerDay *float64 `json:"bytes_per_day,omitempty"`
+	// StorageExhaustedAt is when the storage quota is projected to be exhausted, nil if storage is not growing
+	StorageExhaustedAt *time.Time `json:"storage_exhausted_at,omitempty"`
+}
@@ -0,0 +1,185 @@
+package querysync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"gopkg.in/yaml.v3"
+)
+
+// sidecarExtensions lists the metadata file extensions looked up next to each .sql file, in order of preference
+var sidecarExtensions = []string{".yaml", ".yml", ".json"}
+
+// Parameter is the sidecar representation of a query parameter
+type Parameter struct {
+	Key         string   `yaml:"key" json:"key"`
+	Type        string   `yaml:"type" json:"type"`
+	Value       string   `yaml:"value" json:"value"`
+	EnumOptions []string `yaml:"enum_options,omitempty" json:"enum_options,omitempty"`
+}
+
+// Sidecar holds the metadata of a saved query that lives next to its .sql file
+type Sidecar struct {
+	// QueryID is zero until the query has been created on Dune, push writes it back after creation
+	QueryID     int         `yaml:"query_id,omitempty" json:"query_id,omitempty"`
+	Name        string      `yaml:"name" json:"name"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string    `yaml:"tags,omitempty" json:"tags,omitempty"`
+	IsPrivate   bool        `yaml:"is_private,omitempty" json:"is_private,omitempty"`
+	Archived    bool        `yaml:"archived,omitempty" json:"archived,omitempty"`
+	Parameters  []Parameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+}
+
+// LocalQuery is a saved query as it is stored on disk: a .sql file and its sidecar
+type LocalQuery struct {
+	SQLPath     string
+	SidecarPath string
+	SQL         string
+	Meta        Sidecar
+}
+
+// QueryParameters converts the sidecar parameters to the API representation
+func (q *LocalQuery) QueryParameters() []models.QueryParameter {
+	if len(q.Meta.Parameters) == 0 {
+		return nil
+	}
+	params := make([]models.QueryParameter, 0, len(q.Meta.Parameters))
+	for _, p := range q.Meta.Parameters {
+		params = append(params, models.QueryParameter{
+			Key:         p.Key,
+			Type:        p.Type,
+			Value:       p.Value,
+			EnumOptions: p.EnumOptions,
+		})
+	}
+	return params
+}
+
+// CreateRequest returns the request that creates this query on Dune
+func (q *LocalQuery) CreateRequest() models.CreateQueryRequest {
+	return models.CreateQueryRequest{
+		Name:        q.Meta.Name,
+		QuerySQL:    q.SQL,
+		Description: q.Meta.Description,
+		IsPrivate:   q.Meta.IsPrivate,
+		Tags:        q.Meta.Tags,
+		Parameters:  q.QueryParameters(),
+	}
+}
+
+// LoadDir reads every .sql file in dir together with its sidecar. A .sql file without
+// a sidecar is an error, since the query name is mandatory.
+func LoadDir(dir string) ([]*LocalQuery, error) {
+	sqlPaths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(sqlPaths)
+
+	queries := make([]*LocalQuery, 0, len(sqlPaths))
+	seen := map[int]string{}
+	for _, sqlPath := range sqlPaths {
+		q, err := loadQuery(sqlPath)
+		if err != nil {
+			return nil, err
+		}
+		if q.Meta.QueryID != 0 {
+			if other, ok := seen[q.Meta.QueryID]; ok {
+				return nil, fmt.Errorf("query %d is declared by both %s and %s", q.Meta.QueryID, other, q.SQLPath)
+			}
+			seen[q.Meta.QueryID] = q.SQLPath
+		}
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+func loadQuery(sqlPath string) (*LocalQuery, error) {
+	sql, err := os.ReadFile(sqlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sidecarPath := findSidecar(sqlPath)
+	if sidecarPath == "" {
+		return nil, fmt.Errorf("%s: missing sidecar file (one of %s)", sqlPath, strings.Join(sidecarExtensions, ", "))
+	}
+	data, err := os.ReadFile(sidecarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta Sidecar
+	if filepath.Ext(sidecarPath) == ".json" {
+		err = json.Unmarshal(data, &meta)
+	} else {
+		err = yaml.Unmarshal(data, &meta)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to parse sidecar: %w", sidecarPath, err)
+	}
+	if meta.Name == "" {
+		return nil, fmt.Errorf("%s: missing query name", sidecarPath)
+	}
+
+	return &LocalQuery{
+		SQLPath:     sqlPath,
+		SidecarPath: sidecarPath,
+		SQL:         string(sql),
+		Meta:        meta,
+	}, nil
+}
+
+func findSidecar(sqlPath string) string {
+	base := strings.TrimSuffix(sqlPath, ".sql")
+	for _, ext := range sidecarExtensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// Save writes the .sql file and the sidecar back to disk
+func (q *LocalQuery) Save() error {
+	sql := q.SQL
+	if !strings.HasSuffix(sql, "\n") {
+		sql += "\n"
+	}
+	if err := os.WriteFile(q.SQLPath, []byte(sql), 0o644); err != nil {
+		return err
+	}
+	return q.saveSidecar()
+}
+
+func (q *LocalQuery) saveSidecar() error {
+	var data []byte
+	var err error
+	if filepath.Ext(q.SidecarPath) == ".json" {
+		data, err = json.MarshalIndent(q.Meta, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(q.Meta)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.SidecarPath, data, 0o644)
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a query name into a file name friendly string
+func slugify(name string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return "query"
+	}
+	return slug
+}
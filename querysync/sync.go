@@ -0,0 +1,254 @@
+// Package querysync keeps a directory of .sql files and their metadata sidecars in sync
+// with saved queries on Dune.
+//
+// Every query is a pair of files sharing the same base name: the SQL text in
+// <name>.sql and the metadata in <name>.yaml (or .yml / .json). Once a query has
+// been created on Dune its ID is written back into the sidecar, which makes
+// pushing the same directory again a no-op.
+package querysync
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+)
+
+type Action string
+
+const (
+	ActionNone      Action = "none"
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionArchive   Action = "archive"
+	ActionUnarchive Action = "unarchive"
+	// ActionMissing is planned for queries whose ID no longer exists on Dune, push leaves them
+	// alone until the query_id is removed from the sidecar to create them again
+	ActionMissing Action = "missing"
+)
+
+// Change describes what push would do (or did) for a single local query
+type Change struct {
+	Action Action
+	Local  *LocalQuery
+	// Remote is the current state of the saved query, nil for creations
+	Remote *models.GetQueryResponse
+	// Diff holds the field by field differences between local and remote, nil for creations and archives
+	Diff *dune.QueryDiff
+	// Fields lists the names of the fields that differ between local and remote
+	Fields []string
+}
+
+// Syncer maps a local directory to saved queries
+type Syncer struct {
+	client dune.DuneClient
+	dir    string
+}
+
+func New(client dune.DuneClient, dir string) *Syncer {
+	return &Syncer{
+		client: client,
+		dir:    dir,
+	}
+}
+
+// Plan compares the local directory against Dune and returns one change per local query.
+// Queries deleted on Dune are planned as ActionMissing rather than failing the whole plan.
+func (s *Syncer) Plan() ([]Change, error) {
+	locals, err := LoadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(locals))
+	for _, local := range locals {
+		change, err := s.planQuery(local)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func (s *Syncer) planQuery(local *LocalQuery) (Change, error) {
+	if local.Meta.QueryID == 0 {
+		if local.Meta.Archived {
+			return Change{Action: ActionNone, Local: local}, nil
+		}
+		return Change{Action: ActionCreate, Local: local}, nil
+	}
+
+	remote, err := s.client.GetQuery(local.Meta.QueryID)
+	var httpErr *dune.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		if local.Meta.Archived {
+			return Change{Action: ActionNone, Local: local}, nil
+		}
+		return Change{Action: ActionMissing, Local: local}, nil
+	}
+	if err != nil {
+		return Change{}, fmt.Errorf("%s: failed to get query %d: %w", local.SQLPath, local.Meta.QueryID, err)
+	}
+
+	change := Change{Action: ActionNone, Local: local, Remote: remote}
+	switch {
+	case local.Meta.Archived && !remote.IsArchived:
+		change.Action = ActionArchive
+	case !local.Meta.Archived:
+		change.Diff = dune.DiffCreateQuery(local.CreateRequest(), remote)
+		change.Fields = change.Diff.ChangedFields()
+		if remote.IsArchived {
+			change.Action = ActionUnarchive
+		} else if change.Diff.HasChanges() {
+			change.Action = ActionUpdate
+		}
+	}
+	return change, nil
+}
+
+// Push applies the plan: creates new queries, updates changed ones and archives the ones
+// marked as archived. Query IDs of created queries are written back to their sidecars.
+func (s *Syncer) Push() ([]Change, error) {
+	changes, err := s.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if err := s.apply(change); err != nil {
+			return changes, fmt.Errorf("%s: failed to %s query: %w", change.Local.SQLPath, change.Action, err)
+		}
+	}
+	return changes, nil
+}
+
+func (s *Syncer) apply(change Change) error {
+	local := change.Local
+	switch change.Action {
+	case ActionCreate:
+		resp, err := s.client.CreateQuery(local.CreateRequest())
+		if err != nil {
+			return err
+		}
+		local.Meta.QueryID = resp.QueryID
+		return local.saveSidecar()
+	case ActionUpdate:
+		// refuse to overwrite changes made on Dune since the plan was computed
+		_, err := s.client.UpdateQueryIfVersion(local.Meta.QueryID, change.Remote.Version, updateRequest(local))
+		return err
+	case ActionUnarchive:
+		if _, err := s.client.UnarchiveQuery(local.Meta.QueryID); err != nil {
+			return err
+		}
+		if change.Diff.HasChanges() {
+			_, err := s.client.UpdateQuery(local.Meta.QueryID, updateRequest(local))
+			return err
+		}
+		return nil
+	case ActionArchive:
+		_, err := s.client.ArchiveQuery(local.Meta.QueryID)
+		return err
+	}
+	return nil
+}
+
+// updateRequest sets every field of the saved query, tags and parameters removed locally are sent
+// as empty lists so they are cleared on Dune
+func updateRequest(local *LocalQuery) models.UpdateQueryRequest {
+	req := models.UpdateQueryRequest{
+		Name:        &local.Meta.Name,
+		QuerySQL:    &local.SQL,
+		Description: &local.Meta.Description,
+		IsPrivate:   &local.Meta.IsPrivate,
+		Tags:        local.Meta.Tags,
+		Parameters:  local.QueryParameters(),
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	if req.Parameters == nil {
+		req.Parameters = []models.QueryParameter{}
+	}
+	return req
+}
+
+// Pull overwrites the local files of every query tracked in the directory with their
+// current state on Dune. Additional query IDs are fetched into new files named after
+// the query. It returns the paths of the .sql files written.
+func (s *Syncer) Pull(queryIDs ...int) ([]string, error) {
+	locals, err := LoadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int]*LocalQuery{}
+	ids := []int{}
+	for _, local := range locals {
+		if local.Meta.QueryID != 0 {
+			byID[local.Meta.QueryID] = local
+			ids = append(ids, local.Meta.QueryID)
+		}
+	}
+	for _, id := range queryIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	written := make([]string, 0, len(ids))
+	for _, id := range ids {
+		remote, err := s.client.GetQuery(id)
+		if err != nil {
+			return written, fmt.Errorf("failed to get query %d: %w", id, err)
+		}
+
+		local, ok := byID[id]
+		if !ok {
+			local = s.newLocalQuery(remote)
+		}
+		local.SQL = remote.QuerySQL
+		local.Meta = sidecarFromRemote(remote)
+		if err := local.Save(); err != nil {
+			return written, err
+		}
+		written = append(written, local.SQLPath)
+	}
+	return written, nil
+}
+
+func (s *Syncer) newLocalQuery(remote *models.GetQueryResponse) *LocalQuery {
+	base := filepath.Join(s.dir, slugify(remote.Name))
+	if _, err := os.Stat(base + ".sql"); err == nil {
+		base = fmt.Sprintf("%s_%d", base, remote.QueryID)
+	}
+	return &LocalQuery{
+		SQLPath:     base + ".sql",
+		SidecarPath: base + ".yaml",
+	}
+}
+
+func sidecarFromRemote(remote *models.GetQueryResponse) Sidecar {
+	meta := Sidecar{
+		QueryID:     remote.QueryID,
+		Name:        remote.Name,
+		Description: remote.Description,
+		Tags:        remote.Tags,
+		IsPrivate:   remote.IsPrivate,
+		Archived:    remote.IsArchived,
+	}
+	for _, p := range remote.Parameters {
+		meta.Parameters = append(meta.Parameters, Parameter{
+			Key:         p.Key,
+			Type:        p.Type,
+			Value:       p.Value,
+			EnumOptions: p.EnumOptions,
+		})
+	}
+	return meta
+}
@@ -0,0 +1,150 @@
+package querysync
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duneanalytics/duneapi-client-go/config"
+	"github.com/duneanalytics/duneapi-client-go/dune"
+	"github.com/duneanalytics/duneapi-client-go/models"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) dune.DuneClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return dune.NewDuneClient(&config.Env{
+		APIKey: "test-api-key",
+		Host:   server.URL,
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestPlanAndPush(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "new.sql"), "SELECT 1\n")
+	writeFile(t, filepath.Join(dir, "new.yaml"), "name: New Query\ntags: [a]\n")
+	writeFile(t, filepath.Join(dir, "changed.sql"), "SELECT 2\n")
+	writeFile(t, filepath.Join(dir, "changed.json"), `{"query_id": 10, "name": "Changed"}`)
+	writeFile(t, filepath.Join(dir, "same.sql"), "SELECT 3\n")
+	writeFile(t, filepath.Join(dir, "same.yml"), "query_id: 11\nname: Same\n")
+	writeFile(t, filepath.Join(dir, "old.sql"), "SELECT 4\n")
+	writeFile(t, filepath.Join(dir, "old.yaml"), "query_id: 12\nname: Old\narchived: true\n")
+	writeFile(t, filepath.Join(dir, "deleted.sql"), "SELECT 5\n")
+	writeFile(t, filepath.Join(dir, "deleted.yaml"), "query_id: 13\nname: Deleted\n")
+
+	var created []models.CreateQueryRequest
+	var updated []int
+	var updateBodies []map[string]any
+	var archived []int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v1/query":
+			var req models.CreateQueryRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			created = append(created, req)
+			json.NewEncoder(w).Encode(models.CreateQueryResponse{QueryID: 99})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/query/10":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{
+				QueryID: 10, Name: "Changed", QuerySQL: "SELECT 1", Tags: []string{"stale"},
+			})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/query/11":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{QueryID: 11, Name: "Same", QuerySQL: "SELECT 3"})
+		case r.Method == "GET" && r.URL.Path == "/api/v1/query/12":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{QueryID: 12, Name: "Old", QuerySQL: "SELECT 4"})
+		case r.Method == "PATCH" && r.URL.Path == "/api/v1/query/10":
+			updated = append(updated, 10)
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			updateBodies = append(updateBodies, body)
+			json.NewEncoder(w).Encode(models.UpdateQueryResponse{QueryID: 10})
+		case r.Method == "POST" && r.URL.Path == "/api/v1/query/12/archive":
+			archived = append(archived, 12)
+			json.NewEncoder(w).Encode(models.UpdateQueryResponse{QueryID: 12})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+		}
+	})
+
+	syncer := New(client, dir)
+	changes, err := syncer.Plan()
+	require.NoError(t, err)
+	actions := map[string]Action{}
+	for _, change := range changes {
+		actions[filepath.Base(change.Local.SQLPath)] = change.Action
+	}
+	require.Equal(t, map[string]Action{
+		"changed.sql": ActionUpdate,
+		"deleted.sql": ActionMissing,
+		"new.sql":     ActionCreate,
+		"old.sql":     ActionArchive,
+		"same.sql":    ActionNone,
+	}, actions)
+
+	_, err = syncer.Push()
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.Equal(t, "New Query", created[0].Name)
+	require.Equal(t, "SELECT 1\n", created[0].QuerySQL)
+	require.Equal(t, []string{"a"}, created[0].Tags)
+	require.Equal(t, []int{10}, updated)
+	// tags and parameters removed locally are cleared on Dune
+	require.Equal(t, []any{}, updateBodies[0]["tags"])
+	require.Equal(t, []any{}, updateBodies[0]["parameters"])
+	require.Equal(t, []int{12}, archived)
+
+	// the created query ID is persisted, so the next plan no longer creates it
+	locals, err := LoadDir(dir)
+	require.NoError(t, err)
+	for _, local := range locals {
+		if filepath.Base(local.SQLPath) == "new.sql" {
+			require.Equal(t, 99, local.Meta.QueryID)
+		}
+	}
+}
+
+func TestPull(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tracked.sql"), "SELECT 1\n")
+	writeFile(t, filepath.Join(dir, "tracked.json"), `{"query_id": 10, "name": "Tracked"}`)
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query/10":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{QueryID: 10, Name: "Tracked", QuerySQL: "SELECT 2"})
+		case "/api/v1/query/20":
+			json.NewEncoder(w).Encode(models.GetQueryResponse{
+				QueryID:  20,
+				Name:     "Top Traders!",
+				QuerySQL: "SELECT 3",
+				Tags:     []string{"dex"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+		}
+	})
+
+	written, err := New(client, dir).Pull(20)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "tracked.sql"), filepath.Join(dir, "top_traders.sql")}, written)
+
+	sql, err := os.ReadFile(filepath.Join(dir, "tracked.sql"))
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 2\n", string(sql))
+
+	locals, err := LoadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, locals, 2)
+	require.Equal(t, filepath.Join(dir, "top_traders.yaml"), locals[0].SidecarPath)
+	require.Equal(t, 20, locals[0].Meta.QueryID)
+	require.Equal(t, []string{"dex"}, locals[0].Meta.Tags)
+}